@@ -7,10 +7,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
+	"sync"
 
 	_ "embed"
 
+	"github.com/tmc/tokencount/internal/bytepair"
 	"golang.org/x/text/unicode/norm"
 )
 
@@ -20,10 +24,21 @@ var configDataGZ []byte
 // A Counter counts tokens in text using Claude's tokenization scheme.
 type Counter struct {
 	vocab   map[string]int
+	decoder map[int]string
 	pattern *regexp.Regexp
 	special map[string]int
 }
 
+// An UnknownTokenError reports a token ID that is not in the vocabulary.
+type UnknownTokenError struct {
+	Token int // the unknown token ID
+	Index int // position of the token in the input slice
+}
+
+func (e *UnknownTokenError) Error() string {
+	return fmt.Sprintf("unknown token ID %d at index %d", e.Token, e.Index)
+}
+
 type config struct {
 	PatternStr     string         `json:"pat_str"`
 	SpecialTokens  map[string]int `json:"special_tokens"`
@@ -31,9 +46,24 @@ type config struct {
 	BPERanks       string         `json:"bpe_ranks"`
 }
 
+// loadCounter parses the embedded config once per process.
+// Every Counter shares the resulting tables, which are never modified.
+var loadCounter = sync.OnceValues(newCounter)
+
 // NewCounter creates a new token counter.
-// The returned Counter is safe for concurrent use.
+// The vocabulary is parsed once per process and shared, so repeated calls
+// are cheap. The returned Counter is safe for concurrent use.
 func NewCounter() (*Counter, error) {
+	shared, err := loadCounter()
+	if err != nil {
+		return nil, err
+	}
+	c := *shared
+	return &c, nil
+}
+
+// newCounter parses the embedded config.
+func newCounter() (*Counter, error) {
 	// Decompress the embedded config
 	gr, err := gzip.NewReader(bytes.NewReader(configDataGZ))
 	if err != nil {
@@ -62,8 +92,18 @@ func NewCounter() (*Counter, error) {
 	pattern := regexp.MustCompile(
 		`'[sStTdDmM]|'[rR][eE]|'[vV][eE]|'[lL][lL]|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
 
+	// Invert the vocabulary and special tokens for decoding
+	decoder := make(map[int]string, len(vocab)+len(cfg.SpecialTokens))
+	for tok, id := range vocab {
+		decoder[id] = tok
+	}
+	for tok, id := range cfg.SpecialTokens {
+		decoder[id] = tok
+	}
+
 	return &Counter{
 		vocab:   vocab,
+		decoder: decoder,
 		pattern: pattern,
 		special: cfg.SpecialTokens,
 	}, nil
@@ -82,99 +122,212 @@ func (c *Counter) Encode(text string) []int {
 	text = norm.NFKC.String(text)
 
 	var tokens []int
-	pos := 0
-
-	for pos < len(text) {
-		// Check for special tokens
-		matched := false
-		for tok, id := range c.special {
-			if strings.HasPrefix(text[pos:], tok) {
-				tokens = append(tokens, id)
-				pos += len(tok)
-				matched = true
-				break
-			}
-		}
-		if matched {
-			continue
-		}
-
-		// Match pattern
-		loc := c.pattern.FindStringIndex(text[pos:])
-		if loc == nil {
+	for text != "" {
+		size, id := c.next(text)
+		if size == 0 {
 			break
 		}
-
-		chunk := text[pos : pos+loc[1]]
-		tokens = append(tokens, c.encodeChunk([]byte(chunk))...)
-		pos += len(chunk)
+		if id >= 0 {
+			tokens = append(tokens, id)
+		} else {
+			tokens = bytepair.Append(tokens, c.vocab, text[:size])
+		}
+		text = text[size:]
 	}
 
 	return tokens
 }
 
-// encodeChunk applies BPE to a single chunk of bytes.
-func (c *Counter) encodeChunk(chunk []byte) []int {
-	if len(chunk) == 0 {
-		return nil
+// EncodeWithOffsets is like Encode but also returns the byte offsets of
+// each token in the original, unnormalized text: token i was produced from
+// text[offsets[i][0]:offsets[i][1]]. When normalization expands a character
+// into several tokens, as with "½", each of them spans the whole character.
+func (c *Counter) EncodeWithOffsets(text string) ([]int, [][2]int) {
+	s, rawEnds, normEnds := normalize(text)
+
+	// raw maps a position in s to the start (or, if end is set, the end)
+	// of the input segment containing it.
+	raw := func(pos int, end bool) int {
+		i, found := slices.BinarySearch(normEnds, pos)
+		switch {
+		case found || end:
+			return rawEnds[i]
+		case i == 0:
+			return 0
+		default:
+			return rawEnds[i-1]
+		}
 	}
 
-	// Start with individual bytes
-	parts := make([][]byte, len(chunk))
-	for i := range chunk {
-		parts[i] = chunk[i : i+1]
+	var tokens []int
+	var offsets [][2]int
+	for pos := 0; pos < len(s); {
+		size, id := c.next(s[pos:])
+		if size == 0 {
+			break
+		}
+		first := len(tokens)
+		if id >= 0 {
+			tokens = append(tokens, id)
+			offsets = append(offsets, [2]int{pos, pos + size})
+		} else {
+			tokens, offsets = bytepair.AppendOffsets(tokens, offsets, c.vocab, s[pos:pos+size], pos)
+		}
+		for i := first; i < len(offsets); i++ {
+			offsets[i] = [2]int{raw(offsets[i][0], false), raw(offsets[i][1], true)}
+		}
+		pos += size
 	}
+	return tokens, offsets
+}
 
-	// Repeatedly merge the best pair until no more merges possible
-	for len(parts) > 1 {
-		bestIdx := -1
-		bestRank := int(^uint(0) >> 1) // max int
+// Decode returns the text for the given token IDs. Since Encode normalizes
+// its input, Decode returns the normalized text.
+// Byte sequences that are not valid UTF-8, such as a multi-byte character
+// split across a truncated token sequence, are replaced with U+FFFD.
+// Use DecodeBytes to obtain the raw bytes.
+// If a token ID is not in the vocabulary, Decode returns an *UnknownTokenError.
+func (c *Counter) Decode(tokens []int) (string, error) {
+	b, err := c.DecodeBytes(tokens)
+	if err != nil {
+		return "", err
+	}
+	return string(bytes.ToValidUTF8(b, []byte("\uFFFD"))), nil
+}
 
-		// Find the pair with the lowest rank in vocab
-		for i := 0; i < len(parts)-1; i++ {
-			pair := string(append(parts[i], parts[i+1]...))
-			if rank, ok := c.vocab[pair]; ok && rank < bestRank {
-				bestRank = rank
-				bestIdx = i
-			}
+// DecodeBytes returns the bytes for the given token IDs.
+// Unlike Decode, it does not require the result to be valid UTF-8,
+// so it can be used to decode partial token sequences.
+// If a token ID is not in the vocabulary, DecodeBytes returns an *UnknownTokenError.
+func (c *Counter) DecodeBytes(tokens []int) ([]byte, error) {
+	var b []byte
+	for i, id := range tokens {
+		tok, ok := c.decoder[id]
+		if !ok {
+			return nil, &UnknownTokenError{Token: id, Index: i}
 		}
+		b = append(b, tok...)
+	}
+	return b, nil
+}
 
-		if bestIdx == -1 {
-			break // No more pairs to merge
+// normalize applies NFKC normalization to text segment by segment.
+// It returns the normalized text along with the positions where segments
+// end in the input and in the normalized text. A character that expands
+// to several segments, such as "½", only ends at its last one.
+func normalize(text string) (s string, rawEnds, normEnds []int) {
+	var it norm.Iter
+	it.InitString(norm.NFKC, text)
+	var normalized []byte
+	for last := 0; !it.Done(); {
+		normalized = append(normalized, it.Next()...)
+		if pos := it.Pos(); pos > last {
+			rawEnds = append(rawEnds, pos)
+			normEnds = append(normEnds, len(normalized))
+			last = pos
 		}
-
-		// Merge the best pair
-		merged := append(parts[bestIdx], parts[bestIdx+1]...)
-		parts = append(parts[:bestIdx], append([][]byte{merged}, parts[bestIdx+2:]...)...)
 	}
+	return string(normalized), rawEnds, normEnds
+}
 
-	// Convert parts to token IDs
-	tokens := make([]int, 0, len(parts))
-	for _, part := range parts {
-		if rank, ok := c.vocab[string(part)]; ok {
-			tokens = append(tokens, rank)
+// next returns the length of the first chunk of normalized text.
+// If the chunk is a special token, next also returns its ID; otherwise id is -1.
+// If text has no chunk, next returns 0, -1.
+func (c *Counter) next(text string) (size, id int) {
+	// Check for special tokens
+	for tok, id := range c.special {
+		if strings.HasPrefix(text, tok) {
+			return len(tok), id
 		}
 	}
 
-	return tokens
+	// Match pattern
+	loc := c.pattern.FindStringIndex(text)
+	if loc == nil {
+		return 0, -1
+	}
+	return loc[1], -1
 }
 
-// parseRanks parses the BPE merge ranks from the space-separated format in claude.json.
-// Format: space-separated base64-encoded tokens in rank order.
-func parseRanks(data string) (map[string]int, error) {
-	fields := strings.Fields(data)
-	ranks := make(map[string]int, len(fields))
+// lookahead is the number of bytes of normalized text that must follow a
+// chunk before its bounds are certain. It covers the few characters the
+// pattern looks past the end of a chunk and the longest special token.
+// Whitespace runs are held back whole instead; see CountPrefix.
+const lookahead = 16
 
-	for rank, b64Token := range fields {
-		tokenBytes, err := base64.StdEncoding.DecodeString(b64Token)
-		if err != nil {
-			// Skip non-base64 tokens (like the first token "!" which is literal)
-			ranks[b64Token] = rank
-			continue
+// CountPrefix counts the tokens in a prefix of text whose tokenization
+// cannot be changed by appending more text. It returns the number of
+// tokens and the length of the prefix in bytes. Counting the rest of the
+// text, followed by any text appended later, completes the count.
+//
+// The prefix always ends at a normalization boundary, so characters that
+// may still combine with text appended later are never counted early.
+// CountPrefix lets a stream be counted incrementally; see bpe.Writer.
+func (c *Counter) CountPrefix(text string) (n, size int) {
+	s, rawEnds, normEnds := normalize(text)
+	if len(normEnds) < 2 {
+		return 0, 0
+	}
+
+	// The last segment may still combine with text appended later.
+	stable := normEnds[len(normEnds)-2]
+
+	// The pattern's \s*[\r\n]+ can join a whitespace run of any length
+	// with newlines that follow it, so a run that reaches the end of the
+	// stable text may still grow into a different chunk.
+	stable = len(strings.TrimRight(s[:stable], "\t\n\f\r "))
+
+	var tokens []int
+	count, seg := 0, 0
+	for pos := 0; pos < stable; {
+		chunk, id := c.next(s[pos:])
+		end := pos + chunk
+		if chunk == 0 || end+lookahead > stable {
+			break
+		}
+		if id >= 0 {
+			count++
+		} else {
+			tokens = bytepair.Append(tokens[:0], c.vocab, s[pos:end])
+			count += len(tokens)
 		}
+		pos = end
 
-		ranks[string(tokenBytes)] = rank
+		// Only chunks ending on a segment boundary map back to the input.
+		for normEnds[seg] < end {
+			seg++
+		}
+		if normEnds[seg] == end {
+			n, size = count, rawEnds[seg]
+		}
 	}
+	return n, size
+}
 
+// parseRanks parses the BPE merge ranks from the bpe_ranks field of claude.json.
+// Each line has the form "! offset token...", listing base64-encoded tokens
+// whose ranks are consecutive starting at offset.
+func parseRanks(data string) (map[string]int, error) {
+	ranks := make(map[string]int)
+	for _, line := range strings.Split(data, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) < 2 {
+			return nil, fmt.Errorf("malformed ranks line %q", line)
+		}
+		offset, err := strconv.Atoi(fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid rank offset %q: %w", fields[1], err)
+		}
+		for i, b64Token := range fields[2:] {
+			tokenBytes, err := base64.StdEncoding.DecodeString(b64Token)
+			if err != nil {
+				return nil, fmt.Errorf("invalid token %q: %w", b64Token, err)
+			}
+			ranks[string(tokenBytes)] = offset + i
+		}
+	}
 	return ranks, nil
 }
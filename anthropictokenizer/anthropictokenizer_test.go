@@ -1,6 +1,11 @@
 package anthropictokenizer
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"testing"
+)
 
 func TestCount(t *testing.T) {
 	counter, err := NewCounter()
@@ -144,3 +149,158 @@ func BenchmarkCount(b *testing.B) {
 		})
 	}
 }
+
+func TestNewCounterShared(t *testing.T) {
+	c1, err := NewCounter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c2, err := NewCounter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c1 == c2 {
+		t.Error("NewCounter returned the same *Counter twice")
+	}
+	if reflect.ValueOf(c1.vocab).UnsafePointer() != reflect.ValueOf(c2.vocab).UnsafePointer() {
+		t.Error("counters have separate vocabularies, want shared")
+	}
+}
+
+func BenchmarkNewCounter(b *testing.B) {
+	if _, err := NewCounter(); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewCounter(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestTokenIDsUnique(t *testing.T) {
+	counter, err := NewCounter()
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+
+	seen := make(map[int]string, len(counter.vocab)+len(counter.special))
+	for tok, id := range counter.special {
+		seen[id] = tok
+	}
+	for tok, id := range counter.vocab {
+		if prev, ok := seen[id]; ok {
+			t.Fatalf("token ID %d used by both %q and %q", id, prev, tok)
+		}
+		seen[id] = tok
+	}
+}
+
+func TestCountPrefix(t *testing.T) {
+	counter, err := NewCounter()
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+
+	texts := []string{
+		"½ starts with an expansion, then ﬁ ™ and é (é) combine",
+		"special <EOT> and <META_START> tokens, spaces   and\n\nnewlines",
+		"The year 2024 has 365 days; I'm sure they'll agree.",
+	}
+	for _, text := range texts {
+		want := counter.Count(text)
+		for i := 0; i <= len(text); i++ {
+			n, size := counter.CountPrefix(text[:i])
+			if size > i {
+				t.Fatalf("CountPrefix(%q) size = %d, want <= %d", text[:i], size, i)
+			}
+			if got := n + counter.Count(text[size:]); got != want {
+				t.Fatalf("CountPrefix(%q) = %d, %d; with rest counts %d tokens, want %d", text[:i], n, size, got, want)
+			}
+		}
+	}
+}
+
+func TestEncodeWithOffsets(t *testing.T) {
+	counter, err := NewCounter()
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []string // text covered by each token
+	}{
+		{
+			name:  "simple text",
+			input: "hello world!",
+			want:  []string{"hello", " world", "!"},
+		},
+		{
+			name:  "special token",
+			input: "hi<EOT>",
+			want:  []string{"hi", "<EOT>"},
+		},
+		{
+			name:  "combining character",
+			input: "cafe\u0301!",
+			want:  []string{"c", "afe\u0301", "!"},
+		},
+		{
+			name:  "expansion",
+			input: "x ½",
+			want:  []string{"x", " ", "½", "½", "½"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, offsets := counter.EncodeWithOffsets(tt.input)
+			if want := counter.Encode(tt.input); !slices.Equal(tokens, want) {
+				t.Errorf("EncodeWithOffsets(%q) tokens = %v, want %v", tt.input, tokens, want)
+			}
+			var got []string
+			for _, off := range offsets {
+				got = append(got, tt.input[off[0]:off[1]])
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("EncodeWithOffsets(%q) spans = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	counter, err := NewCounter()
+	if err != nil {
+		t.Fatalf("NewCounter: %v", err)
+	}
+
+	texts := []string{
+		"hello world!",
+		"special <EOT> token",
+		"Unicode: héllo, 世界, 🎉",
+	}
+	for _, text := range texts {
+		got, err := counter.Decode(counter.Encode(text))
+		if err != nil {
+			t.Fatalf("Decode(Encode(%q)): %v", text, err)
+		}
+		if got != text {
+			t.Errorf("Decode(Encode(%q)) = %q", text, got)
+		}
+	}
+
+	// Decoding returns normalized text.
+	if got, _ := counter.Decode(counter.Encode("ﬁ ½")); got != "fi 1⁄2" {
+		t.Errorf("Decode(Encode(%q)) = %q, want %q", "ﬁ ½", got, "fi 1⁄2")
+	}
+
+	_, err = counter.Decode([]int{9381, -1})
+	var unknown *UnknownTokenError
+	if !errors.As(err, &unknown) || unknown.Token != -1 || unknown.Index != 1 {
+		t.Errorf("Decode with unknown token: err = %v, want *UnknownTokenError for -1 at index 1", err)
+	}
+}
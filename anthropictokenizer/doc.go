@@ -16,5 +16,8 @@
 //	}
 //	count := counter.Count("Hello, Claude!")
 //
+// Encode returns the token IDs of text, and Decode turns them back into
+// the normalized text.
+//
 // The Counter is safe for concurrent use.
 package anthropictokenizer
@@ -38,7 +38,21 @@ func ExampleCounter_Encode() {
 
 	tokens := counter.Encode("hello world!")
 	fmt.Printf("%d tokens: %v\n", len(tokens), tokens)
-	// Output: 3 tokens: [9378 2250 2]
+	// Output: 3 tokens: [9381 2253 5]
+}
+
+func ExampleCounter_Decode() {
+	counter, err := anthropictokenizer.NewCounter()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text, err := counter.Decode([]int{9381, 2253, 5})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(text)
+	// Output: hello world!
 }
 
 func ExampleNewCounter() {
@@ -4,7 +4,6 @@ import (
 	"fmt"
 	"io"
 
-	"github.com/tmc/tokencount/anthropictokenizer"
 	"github.com/tmc/tokencount/openaitokenizer"
 )
 
@@ -17,14 +16,54 @@ type Counter interface {
 type Encoder interface {
 	Counter
 	Encode(text string) []int
+
+	// EncodeWithOffsets is like Encode but also returns the byte offsets
+	// of each token in text: token i was produced from
+	// text[offsets[i][0]:offsets[i][1]].
+	EncodeWithOffsets(text string) (tokens []int, offsets [][2]int)
+}
+
+// A Decoder is an Encoder that can also convert token IDs back to text.
+// Not every Encoder supports decoding; use a type assertion to check:
+//
+//	if dec, ok := enc.(bpe.Decoder); ok {
+//		text, err := dec.Decode(tokens)
+//		...
+//	}
+type Decoder interface {
+	Encoder
+	Decode(tokens []int) (string, error)
+}
+
+// A prefixCounter can count the tokens in a prefix of its input that more
+// input cannot change. Encoders that implement it are counted incrementally
+// by a Writer.
+//
+// CountPrefix returns the number of tokens in the prefix and its length in bytes.
+type prefixCounter interface {
+	CountPrefix(text string) (n, size int)
 }
 
 // A Writer counts tokens as data is written to it.
 // Create a Writer using NewWriter; the zero value is not usable.
+//
+// Text is tokenized incrementally: as soon as the tokenization of a prefix
+// of the input can no longer change, its tokens are counted and the prefix
+// is discarded. Only the trailing, still ambiguous part of the input is
+// held in memory, so memory use is bounded by the longest pre-tokenization
+// chunk rather than by the input size. A chunk that never ends, such as a
+// long run of whitespace or a minified line with no split point, is held
+// in full. With an encoder that cannot count a prefix of its input, as
+// may be the case for encodings added with Register, the whole input is
+// held.
 type Writer struct {
-	enc Counter
-	buf []byte
-	n   int
+	enc  Counter
+	buf  []byte // input not yet counted
+	n    int    // tokens in the input before buf
+	held int    // len(buf) after the last flush
+
+	pending int  // tokens in buf, if counted is set
+	counted bool // whether pending is up to date
 }
 
 // NewWriter returns a Writer that counts tokens using the named encoding.
@@ -40,25 +79,55 @@ func NewWriter(encoding string) (*Writer, error) {
 // It always returns len(p), nil.
 func (w *Writer) Write(p []byte) (n int, err error) {
 	w.buf = append(w.buf, p...)
+	w.counted = false
+	w.flush()
 	return len(p), nil
 }
 
+// flush counts and discards the prefix of buf that later input cannot
+// retokenize.
+func (w *Writer) flush() {
+	pc, ok := w.enc.(prefixCounter)
+	if !ok {
+		return
+	}
+	// Rescanning held input on every small write would be quadratic,
+	// so wait until the buffer has doubled.
+	if len(w.buf) < 2*w.held {
+		return
+	}
+	n, size := pc.CountPrefix(string(w.buf))
+	w.n += n
+	w.buf = w.buf[:copy(w.buf, w.buf[size:])]
+	w.held = len(w.buf)
+}
+
 // Count returns the number of tokens written so far.
+// Text held back because more input could change its tokenization is
+// counted as if the input ended there.
 func (w *Writer) Count() int {
-	if len(w.buf) == 0 {
-		return w.n
+	if !w.counted {
+		w.pending = 0
+		if len(w.buf) > 0 {
+			w.pending = w.enc.Count(string(w.buf))
+		}
+		w.counted = true
 	}
-	return w.enc.Count(string(w.buf))
+	return w.n + w.pending
 }
 
 // Reset resets the Writer to be empty.
 func (w *Writer) Reset() {
 	w.buf = w.buf[:0]
 	w.n = 0
+	w.held = 0
+	w.pending = 0
+	w.counted = false
 }
 
 // CountReader counts tokens from an io.Reader.
-// It reads the entire contents into memory.
+// The input is tokenized as it is read, so memory use is bounded by the
+// longest pre-tokenization chunk rather than by the input size; see Writer.
 func CountReader(r io.Reader, encoding string) (int, error) {
 	w, err := NewWriter(encoding)
 	if err != nil {
@@ -72,28 +141,28 @@ func CountReader(r io.Reader, encoding string) (int, error) {
 
 // NewEncoder returns an encoder for the named tokenizer.
 //
-// Supported encodings:
+// Built-in encodings:
 //   - "anthropic" or "claude": Anthropic's Claude tokenizer
 //   - "o200k_base": OpenAI GPT-4o and newer (default)
 //   - "cl100k_base": OpenAI GPT-4, GPT-3.5-turbo
 //   - "p50k_base": OpenAI Codex models
 //   - "r50k_base": OpenAI GPT-3 models
+//
+// Encodings added with Register, and custom vocabularies added with
+// openaitokenizer.Register, are available under their registered names.
+// Encodings lists them all.
 func NewEncoder(name string) (Encoder, error) {
-	switch name {
-	case "anthropic", "claude":
-		counter, err := anthropictokenizer.NewCounter()
-		if err != nil {
-			return nil, fmt.Errorf("failed to create anthropic tokenizer: %w", err)
-		}
-		return &anthropicEncoder{counter}, nil
-	case "o200k_base", "cl100k_base", "p50k_base", "r50k_base", "":
-		if name == "" {
-			name = "o200k_base"
-		}
-		return openaitokenizer.NewEncoder(name)
-	default:
-		return nil, fmt.Errorf("unknown encoding %q", name)
+	if name == "" {
+		name = DefaultEncoding
+	}
+	if factory, ok := lookup(name); ok {
+		return factory()
 	}
+	// Custom vocabularies added with openaitokenizer.Register
+	if enc, err := openaitokenizer.NewEncoder(name); err == nil {
+		return enc, nil
+	}
+	return nil, fmt.Errorf("unknown encoding %q", name)
 }
 
 // NewCounter returns a counter for the named tokenizer.
@@ -101,15 +170,3 @@ func NewEncoder(name string) (Encoder, error) {
 func NewCounter(name string) (Counter, error) {
 	return NewEncoder(name)
 }
-
-// anthropicEncoder wraps anthropictokenizer.Counter to implement Encoder.
-type anthropicEncoder struct {
-	*anthropictokenizer.Counter
-}
-
-// Encode is not supported for Anthropic tokenizer.
-// Use Count() instead for token counting.
-func (e *anthropicEncoder) Encode(text string) []int {
-	// Anthropic tokenizer doesn't expose token IDs, only counts
-	panic("Encode not supported for Anthropic tokenizer, use Count() instead")
-}
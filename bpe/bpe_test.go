@@ -1,9 +1,14 @@
 package bpe
 
 import (
+	"encoding/base64"
+	"fmt"
 	"io"
 	"strings"
+	"sync"
 	"testing"
+
+	"github.com/tmc/tokencount/openaitokenizer"
 )
 
 func TestNewEncoder(t *testing.T) {
@@ -146,3 +151,187 @@ func TestCountReader(t *testing.T) {
 		})
 	}
 }
+
+func TestEncodeAll(t *testing.T) {
+	for _, name := range []string{"anthropic", "claude", "o200k_base", "cl100k_base", "p50k_base", "r50k_base"} {
+		t.Run(name, func(t *testing.T) {
+			enc, err := NewEncoder(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			text := "hello world"
+			tokens := enc.Encode(text)
+			if len(tokens) != enc.Count(text) {
+				t.Errorf("len(Encode(%q)) = %d, want Count = %d", text, len(tokens), enc.Count(text))
+			}
+		})
+	}
+}
+
+func TestDecoder(t *testing.T) {
+	tests := []struct {
+		encoding string
+		decoder  bool
+	}{
+		{"anthropic", true},
+		{"o200k_base", true},
+		{"cl100k_base", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding, func(t *testing.T) {
+			enc, err := NewEncoder(tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			dec, ok := enc.(Decoder)
+			if ok != tt.decoder {
+				t.Fatalf("NewEncoder(%q) implements Decoder = %v, want %v", tt.encoding, ok, tt.decoder)
+			}
+			if !ok {
+				return
+			}
+
+			text := "hello world"
+			got, err := dec.Decode(enc.Encode(text))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != text {
+				t.Errorf("Decode(Encode(%q)) = %q", text, got)
+			}
+		})
+	}
+}
+
+func TestWriterIncremental(t *testing.T) {
+	texts := []string{
+		"½ The quick brown fox jumps over the lazy dog.",
+		"don't DON'T we'll they've I'm",
+		"spaces   between   words\n\n\ttabs\r\nand\nnewlines   ",
+		"numbers 1234567890 3.14159 -42",
+		"Unicode: héllo, 世界　全角, 🎉 é ﬁ ™ ½",
+		"special <EOT> tokens <|endoftext|> here",
+		"func main() {\n\tfmt.Println(\"hello\")\n}\n",
+	}
+	text := strings.Repeat(strings.Join(texts, " "), 5) +
+		// Long whitespace runs, as in indented blank lines, can be joined
+		// with newlines far past where they start.
+		"x\n" + strings.Repeat(" ", 40) + "\n\ny" +
+		"x\n" + strings.Repeat("\t", 40) + "\ny" +
+		".\n\n" + strings.Repeat("\n", 40) + "z" +
+		"end" + strings.Repeat(" ", 40) + "w"
+
+	for _, encoding := range []string{"anthropic", "o200k_base", "cl100k_base", "r50k_base"} {
+		t.Run(encoding, func(t *testing.T) {
+			enc, err := NewEncoder(encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := enc.Count(text)
+
+			for _, size := range []int{1, 2, 3, 7, 30, 64, 1000} {
+				w, err := NewWriter(encoding)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for i := 0; i < len(text); i += size {
+					io.WriteString(w, text[i:min(i+size, len(text))])
+				}
+				if got := w.Count(); got != want {
+					t.Errorf("Count() after %d-byte writes = %d, want %d", size, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestWriterBoundedMemory(t *testing.T) {
+	for _, encoding := range []string{"anthropic", "o200k_base"} {
+		t.Run(encoding, func(t *testing.T) {
+			w, err := NewWriter(encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			line := "The quick brown fox jumps over the lazy dog.\n"
+			maxBuf := 0
+			for range 20000 {
+				io.WriteString(w, line)
+				maxBuf = max(maxBuf, len(w.buf))
+			}
+			if maxBuf > 1024 {
+				t.Errorf("Writer held %d bytes, want at most 1024", maxBuf)
+			}
+			if got, want := w.Count(), 20000*w.enc.Count(line); got != want {
+				t.Errorf("Count() = %d, want %d", got, want)
+			}
+		})
+	}
+}
+
+func BenchmarkCountReader(b *testing.B) {
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog.\n", 10000)
+	for _, encoding := range []string{"anthropic", "o200k_base"} {
+		b.Run(encoding, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				if _, err := CountReader(strings.NewReader(text), encoding); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestEncodeWithOffsets(t *testing.T) {
+	text := "Hello, wörld! ½ <EOT>"
+	for _, name := range []string{"anthropic", "o200k_base", "cl100k_base", "p50k_base", "r50k_base"} {
+		t.Run(name, func(t *testing.T) {
+			enc, err := NewEncoder(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			tokens, offsets := enc.EncodeWithOffsets(text)
+			if len(tokens) != len(offsets) || len(tokens) != enc.Count(text) {
+				t.Fatalf("EncodeWithOffsets returned %d tokens and %d offsets, want %d", len(tokens), len(offsets), enc.Count(text))
+			}
+			prev := 0
+			for i, off := range offsets {
+				if off[0] < prev || off[0] > off[1] || off[1] > len(text) {
+					t.Errorf("token %d has offsets %v after %d, want ordered spans within text", i, off, prev)
+				}
+				prev = off[0]
+			}
+		})
+	}
+}
+
+// registerBytes registers the encoding of TestNewEncoderCustom once,
+// since openaitokenizer has no way to remove an encoding from outside the
+// package and registering a name twice panics.
+var registerBytes sync.Once
+
+func TestNewEncoderCustom(t *testing.T) {
+	// A vocabulary of single bytes: every byte is its own token.
+	var ranks strings.Builder
+	for b := range 256 {
+		fmt.Fprintf(&ranks, "%s %d\n", base64.StdEncoding.EncodeToString([]byte{byte(b)}), b)
+	}
+	enc, err := openaitokenizer.NewEncoderFromReader(strings.NewReader(ranks.String()), `\S+|\s+`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	const name = "test_bytes"
+	registerBytes.Do(func() { openaitokenizer.Register(name, enc) })
+
+	got, err := NewEncoder(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := got.Count("hello world"); n != 11 {
+		t.Errorf("Count = %d, want 11", n)
+	}
+}
@@ -0,0 +1,208 @@
+package chat
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// A Message is one message of a chat request.
+type Message struct {
+	Role    string `json:"role"`           // "system", "user", "assistant", ...
+	Content string `json:"content"`        // text of the message
+	Name    string `json:"name,omitempty"` // optional name of the author
+
+	Images    []Image    `json:"images,omitempty"`    // attached images
+	Documents []Document `json:"documents,omitempty"` // attached documents
+}
+
+// overhead describes how a model frames each message in the prompt.
+type overhead struct {
+	perMessage int // tokens added for every message
+	perName    int // tokens added when a message has a name
+}
+
+// overheads lists the models whose framing differs from defaultOverhead.
+var overheads = map[string]overhead{
+	// gpt-3.5-turbo-0301 frames every message as
+	// <|start|>{role/name}\n{content}<|end|>\n, and the name replaces the role.
+	"gpt-3.5-turbo-0301": {perMessage: 4, perName: -1},
+}
+
+// defaultOverhead is the framing used by gpt-3.5-turbo-0613, gpt-4, gpt-4o,
+// and later OpenAI chat models.
+var defaultOverhead = overhead{perMessage: 3, perName: 1}
+
+// replyPrimer is the number of tokens that prime every reply with
+// <|start|>assistant<|message|>.
+const replyPrimer = 3
+
+// A Request is a chat request to a model.
+type Request struct {
+	Model    string    // model name, resolved with bpe.ForModel
+	Messages []Message // conversation so far
+	Tools    []Tool    // tools the model may call
+}
+
+// CountMessages returns the number of prompt tokens that the named model
+// uses for messages. The model is resolved with bpe.ForModel.
+//
+// For OpenAI models the count includes the per-message, per-name, and
+// reply priming tokens documented in OpenAI's cookbook. For Claude models
+// it is an estimate; see the package documentation.
+func CountMessages(model string, messages []Message) (int, error) {
+	return CountRequest(Request{Model: model, Messages: messages})
+}
+
+// CountRequest returns the number of prompt tokens used by req, including
+// its messages, the definitions of its tools, and attached images and
+// documents. It is EstimateRequest(req).Total().
+func CountRequest(req Request) (int, error) {
+	u, err := EstimateRequest(req)
+	return u.Total(), err
+}
+
+// A Usage is an estimate of the prompt tokens used by a request.
+type Usage struct {
+	Messages  int // text of the messages, including their framing
+	Tools     int // tool definitions
+	Images    int // attached images
+	Documents int // attached documents
+
+	// Approximate reports that the estimate is not exact, as for Claude
+	// models and for requests with documents.
+	Approximate bool
+}
+
+// Total returns the total number of prompt tokens.
+func (u Usage) Total() int {
+	return u.Messages + u.Tools + u.Images + u.Documents
+}
+
+// EstimateRequest returns an estimate of the prompt tokens used by req,
+// broken down by kind. Text is counted with the model's bpe.Encoder, and
+// images and documents with ImageTokens and DocumentTokens.
+func EstimateRequest(req Request) (Usage, error) {
+	m, enc, err := encoderFor(req.Model)
+	if err != nil {
+		return Usage{}, err
+	}
+
+	var u Usage
+	for _, msg := range req.Messages {
+		for _, img := range msg.Images {
+			u.Images += imageTokens(m, img)
+		}
+		for _, doc := range msg.Documents {
+			u.Documents += documentTokens(m, enc, doc)
+			u.Approximate = true
+		}
+	}
+
+	if m.Encoding == "anthropic" {
+		u.Approximate = true
+		if u.Messages, err = countAnthropic(enc, req.Messages); err != nil {
+			return Usage{}, err
+		}
+		if u.Tools, err = countAnthropicTools(enc, m.Name, req.Tools); err != nil {
+			return Usage{}, err
+		}
+		return u, nil
+	}
+
+	if u.Tools, err = countOpenAITools(enc, req.Tools); err != nil {
+		return Usage{}, err
+	}
+	padSystem := len(req.Tools) > 0
+	if padSystem && slices.ContainsFunc(req.Messages, isSystem) {
+		// The system message and the tool definitions share their framing,
+		// which hmarr/openai-chat-tokens accounts for by subtracting four
+		// tokens; checked there against gpt-3.5-turbo and gpt-4.
+		u.Tools -= 4
+	}
+	u.Messages = countOpenAI(enc, modelOverhead(m.Name), req.Messages, padSystem)
+	return u, nil
+}
+
+func isSystem(msg Message) bool { return msg.Role == "system" }
+
+// encoderFor returns the encoder used by the named model.
+func encoderFor(model string) (bpe.Model, bpe.Encoder, error) {
+	m, err := bpe.ForModel(model)
+	if err != nil {
+		return m, nil, err
+	}
+	enc, err := bpe.NewEncoder(m.Encoding)
+	return m, enc, err
+}
+
+// baseModel strips any provider prefix, such as "openai/", from model.
+func baseModel(model string) string {
+	if i := strings.LastIndexByte(model, '/'); i >= 0 {
+		return model[i+1:]
+	}
+	return model
+}
+
+// modelOverhead returns the message framing used by model.
+func modelOverhead(model string) overhead {
+	if o, ok := overheads[baseModel(model)]; ok {
+		return o
+	}
+	return defaultOverhead
+}
+
+// countOpenAI counts messages as OpenAI's cookbook does: every message
+// costs its framing plus the tokens of its role, content, and name.
+// If padSystem is set, because tool definitions are rendered into the
+// system prompt, the first system message is joined to them with a newline.
+func countOpenAI(enc bpe.Counter, o overhead, messages []Message, padSystem bool) int {
+	n := replyPrimer
+	for _, msg := range messages {
+		content := msg.Content
+		if padSystem && isSystem(msg) {
+			content += "\n"
+			padSystem = false
+		}
+		n += o.perMessage + enc.Count(msg.Role) + enc.Count(content)
+		if msg.Name != "" {
+			n += o.perName + enc.Count(msg.Name)
+		}
+	}
+	return n
+}
+
+// countAnthropic estimates the tokens of messages by rendering them in
+// the Human/Assistant turn format used by Claude's text completions:
+//
+//	{system}\n\nHuman: {user}\n\nAssistant: {assistant}...\n\nAssistant:
+//
+// System messages are gathered into the system prompt at the start, as
+// the Messages API does with its system parameter. If the last message is
+// from the assistant, it is treated as a prefilled reply.
+func countAnthropic(enc bpe.Counter, messages []Message) (int, error) {
+	var system []string
+	var b strings.Builder
+	last := ""
+	for _, msg := range messages {
+		switch msg.Role {
+		case "system":
+			system = append(system, msg.Content)
+			continue
+		case "user":
+			b.WriteString("\n\nHuman: ")
+		case "assistant":
+			b.WriteString("\n\nAssistant: ")
+		default:
+			return 0, fmt.Errorf("unsupported role %q for Claude models", msg.Role)
+		}
+		b.WriteString(msg.Content)
+		last = msg.Role
+	}
+	if last != "assistant" {
+		b.WriteString("\n\nAssistant:")
+	}
+	return enc.Count(strings.Join(system, "\n\n") + b.String()), nil
+}
@@ -0,0 +1,106 @@
+package chat
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// cookbookMessages are the example messages from OpenAI's cookbook
+// "How to count tokens with tiktoken".
+var cookbookMessages = []Message{
+	{Role: "system", Content: "You are a helpful, pattern-following assistant that translates corporate jargon into plain English."},
+	{Role: "system", Name: "example_user", Content: "New synergies will help drive top-line growth."},
+	{Role: "system", Name: "example_assistant", Content: "Things working well together will increase revenue."},
+	{Role: "system", Name: "example_user", Content: "Let's circle back when we have more bandwidth to touch base on opportunities for increased leverage."},
+	{Role: "system", Name: "example_assistant", Content: "Let's talk later when we're less busy about how to do better."},
+	{Role: "user", Content: "This late pivot means we don't have time to boil the ocean for the client deliverable."},
+}
+
+func TestCountMessagesOpenAI(t *testing.T) {
+	// Prompt token counts reported by the API, from the cookbook.
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"gpt-3.5-turbo-0301", 127},
+		{"gpt-3.5-turbo-0613", 129},
+		{"gpt-3.5-turbo", 129},
+		{"gpt-4-0613", 129},
+		{"gpt-4", 129},
+		{"gpt-4o", 124},
+		{"gpt-4o-mini", 124},
+		{"openai/gpt-4o", 124},
+	}
+	for _, tt := range tests {
+		got, err := CountMessages(tt.model, cookbookMessages)
+		if err != nil {
+			t.Errorf("CountMessages(%q): %v", tt.model, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CountMessages(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+}
+
+func TestCountMessagesEmpty(t *testing.T) {
+	if got, err := CountMessages("gpt-4o", nil); err != nil || got != replyPrimer {
+		t.Errorf("CountMessages(nil) = %d, %v, want %d, nil", got, err, replyPrimer)
+	}
+}
+
+func TestCountMessagesAnthropic(t *testing.T) {
+	enc, err := bpe.NewEncoder("anthropic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		messages []Message
+		rendered string
+	}{
+		{
+			[]Message{{Role: "user", Content: "Hello!"}},
+			"\n\nHuman: Hello!\n\nAssistant:",
+		},
+		{
+			[]Message{
+				{Role: "system", Content: "You are terse."},
+				{Role: "user", Content: "Hello!"},
+				{Role: "assistant", Content: "Hi."},
+				{Role: "system", Content: "Never apologize."},
+				{Role: "user", Content: "How are you?"},
+			},
+			"You are terse.\n\nNever apologize.\n\nHuman: Hello!\n\nAssistant: Hi.\n\nHuman: How are you?\n\nAssistant:",
+		},
+		{
+			[]Message{
+				{Role: "user", Content: "Answer in JSON."},
+				{Role: "assistant", Content: "{"},
+			},
+			"\n\nHuman: Answer in JSON.\n\nAssistant: {",
+		},
+	}
+	for _, tt := range tests {
+		got, err := CountMessages("claude-3-5-sonnet-20241022", tt.messages)
+		if err != nil {
+			t.Errorf("CountMessages(%v): %v", tt.messages, err)
+			continue
+		}
+		if want := enc.Count(tt.rendered); got != want {
+			t.Errorf("CountMessages(%v) = %d, want %d for %q", tt.messages, got, want, tt.rendered)
+		}
+	}
+
+	_, err = CountMessages("claude-3-opus", []Message{{Role: "tool", Content: "42"}})
+	if err == nil || !strings.Contains(err.Error(), `"tool"`) {
+		t.Errorf("CountMessages with tool role: err = %v, want unsupported role error", err)
+	}
+}
+
+func TestCountMessagesUnknownModel(t *testing.T) {
+	if _, err := CountMessages("no-such-model", cookbookMessages); err == nil {
+		t.Error("CountMessages(no-such-model) succeeded, want error")
+	}
+}
@@ -0,0 +1,32 @@
+// Package chat counts the tokens in chat requests.
+//
+// Chat models see more than the text of each message: the request is
+// rendered into a prompt with role markers, separators, and a primer for
+// the reply, all of which count against the context window. CountMessages
+// adds this overhead to the token counts of the messages themselves.
+//
+// Basic usage:
+//
+//	n, err := chat.CountMessages("gpt-4o", []chat.Message{
+//	    {Role: "system", Content: "You are a helpful assistant."},
+//	    {Role: "user", Content: "Hello!"},
+//	})
+//
+// For OpenAI models the count reproduces the ChatML overhead documented in
+// OpenAI's cookbook and matches the prompt tokens reported by the API.
+// Anthropic does not document how the Messages API renders a request, so
+// for Claude models the count is an estimate made by rendering the
+// messages in the Human/Assistant turn format and counting the result.
+//
+// Tool definitions also count against the context window. CountTools
+// counts them on their own, and CountRequest counts a whole Request of
+// messages and tools. Tools can be unmarshaled directly from OpenAI or
+// Anthropic JSON definitions.
+//
+// Messages may carry images and documents. ImageTokens applies OpenAI's
+// tile formula or Anthropic's one token per 750 pixels rule to an Image,
+// whose size can be read from a PNG, JPEG, or GIF file with OpenImage.
+// DocumentTokens estimates a Document, such as a PDF read with
+// OpenDocument, as its text plus an image of each page. EstimateRequest
+// combines all of these into a Usage for the whole request.
+package chat
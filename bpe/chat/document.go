@@ -0,0 +1,92 @@
+package chat
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// A Document is a document, such as a PDF, attached to a message.
+//
+// Both OpenAI and Anthropic models see each page of a PDF twice: as its
+// extracted text and as an image of the page.
+type Document struct {
+	Pages int    `json:"pages,omitempty"` // number of pages; 0 for plain text
+	Text  string `json:"text,omitempty"`  // extracted text, if known
+}
+
+// DecodeDocument reads a PDF or plain text document from r.
+//
+// The pages of a PDF are found by scanning its objects, which fails for
+// PDFs that keep their page objects in compressed object streams. The
+// text of a PDF is not extracted.
+func DecodeDocument(r io.Reader) (Document, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return Document{}, err
+	}
+	if bytes.HasPrefix(data, []byte("%PDF-")) {
+		n := len(pdfPage.FindAllIndex(data, -1))
+		if n == 0 {
+			return Document{}, errors.New("cannot find PDF pages (compressed object streams are not supported)")
+		}
+		return Document{Pages: n}, nil
+	}
+	if !utf8.Valid(data) {
+		return Document{}, errors.New("unsupported document: not a PDF or UTF-8 text")
+	}
+	return Document{Text: string(data)}, nil
+}
+
+// pdfPage matches the type entry of a PDF page object, but not of the
+// page tree nodes (/Type /Pages).
+var pdfPage = regexp.MustCompile(`/Type\s*/Page\b`)
+
+// OpenDocument reads the named PDF or plain text file.
+func OpenDocument(name string) (Document, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Document{}, err
+	}
+	defer f.Close()
+	doc, err := DecodeDocument(f)
+	if err != nil {
+		return Document{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return doc, nil
+}
+
+// pageImage is the size at which a page is assumed to be rendered: a
+// US Letter page at 150 dots per inch.
+var pageImage = Image{Width: 1275, Height: 1650}
+
+// pageText is the number of tokens assumed for the text of a page when
+// the text of a document is not known. A dense page of prose holds about
+// 500 words.
+const pageText = 650
+
+// DocumentTokens returns an estimate of the number of tokens that the
+// named model uses for doc: the tokens of its text plus an image of each
+// page. If the text of a paged document is not known, each page is
+// assumed to hold a page of prose. The model is resolved with bpe.ForModel.
+func DocumentTokens(model string, doc Document) (int, error) {
+	m, enc, err := encoderFor(model)
+	if err != nil {
+		return 0, err
+	}
+	return documentTokens(m, enc, doc), nil
+}
+
+func documentTokens(m bpe.Model, enc bpe.Counter, doc Document) int {
+	n := doc.Pages * imageTokens(m, pageImage)
+	if doc.Text == "" {
+		return n + doc.Pages*pageText
+	}
+	return n + enc.Count(doc.Text)
+}
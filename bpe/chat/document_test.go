@@ -0,0 +1,117 @@
+package chat
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// twoPagePDF is the object structure of a minimal two-page PDF.
+const twoPagePDF = `%PDF-1.4
+1 0 obj << /Type /Catalog /Pages 2 0 R >> endobj
+2 0 obj << /Type /Pages /Kids [3 0 R 4 0 R] /Count 2 >> endobj
+3 0 obj << /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] >> endobj
+4 0 obj <</Type/Page/Parent 2 0 R/MediaBox [0 0 612 792]>> endobj
+trailer << /Root 1 0 R >>
+%%EOF
+`
+
+func TestDecodeDocument(t *testing.T) {
+	tests := []struct {
+		data string
+		want Document
+	}{
+		{twoPagePDF, Document{Pages: 2}},
+		{"plain text\n", Document{Text: "plain text\n"}},
+	}
+	for _, tt := range tests {
+		got, err := DecodeDocument(strings.NewReader(tt.data))
+		if err != nil {
+			t.Errorf("DecodeDocument(%.20q): %v", tt.data, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("DecodeDocument(%.20q) = %+v, want %+v", tt.data, got, tt.want)
+		}
+	}
+
+	for _, data := range []string{"%PDF-1.7\n% no pages\n", "\xff\xfe binary"} {
+		if doc, err := DecodeDocument(strings.NewReader(data)); err == nil {
+			t.Errorf("DecodeDocument(%q) = %+v, want error", data, doc)
+		}
+	}
+}
+
+func TestDocumentTokens(t *testing.T) {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	page, _ := ImageTokens("gpt-4o", pageImage)
+	tests := []struct {
+		doc  Document
+		want int
+	}{
+		{Document{Text: "Hello, world!"}, enc.Count("Hello, world!")},
+		{Document{Pages: 2, Text: "Hello, world!"}, 2*page + enc.Count("Hello, world!")},
+		{Document{Pages: 2}, 2 * (page + pageText)},
+	}
+	for _, tt := range tests {
+		got, err := DocumentTokens("gpt-4o", tt.doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("DocumentTokens(%+v) = %d, want %d", tt.doc, got, tt.want)
+		}
+	}
+}
+
+func TestEstimateRequest(t *testing.T) {
+	img := Image{Width: 1024, Height: 1024}
+	doc := Document{Text: "Quarterly report"}
+	text := []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "Compare these."},
+	}
+	attached := slices.Clone(text)
+	attached[1].Images = []Image{img, img}
+	attached[1].Documents = []Document{doc}
+	tools := []Tool{{Name: "foo"}}
+
+	for _, model := range []string{"gpt-4o", "claude-sonnet-4-5"} {
+		plain, err := EstimateRequest(Request{Model: model, Messages: text, Tools: tools})
+		if err != nil {
+			t.Fatalf("EstimateRequest(%s): %v", model, err)
+		}
+		u, err := EstimateRequest(Request{Model: model, Messages: attached, Tools: tools})
+		if err != nil {
+			t.Fatalf("EstimateRequest(%s): %v", model, err)
+		}
+		image, _ := ImageTokens(model, img)
+		document, _ := DocumentTokens(model, doc)
+		want := Usage{
+			Messages:    plain.Messages,
+			Tools:       plain.Tools,
+			Images:      2 * image,
+			Documents:   document,
+			Approximate: true,
+		}
+		if u != want {
+			t.Errorf("EstimateRequest(%s) = %+v, want %+v", model, u, want)
+		}
+		if got, _ := CountRequest(Request{Model: model, Messages: attached, Tools: tools}); got != u.Total() {
+			t.Errorf("CountRequest(%s) = %d, want Total() = %d", model, got, u.Total())
+		}
+	}
+
+	u, err := EstimateRequest(Request{Model: "gpt-4o", Messages: text})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Approximate {
+		t.Errorf("EstimateRequest(gpt-4o, text only) = %+v, want exact", u)
+	}
+}
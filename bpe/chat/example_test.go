@@ -0,0 +1,67 @@
+package chat_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/tmc/tokencount/bpe/chat"
+)
+
+func ExampleCountMessages() {
+	messages := []chat.Message{
+		{Role: "system", Content: "You are a helpful assistant."},
+		{Role: "user", Content: "Hello, world!"},
+	}
+	n, err := chat.CountMessages("gpt-4o", messages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d tokens\n", n)
+	// Output: 21 tokens
+}
+
+func ExampleCountTools() {
+	var tools []chat.Tool
+	err := json.Unmarshal([]byte(`[{
+		"type": "function",
+		"function": {
+			"name": "get_weather",
+			"description": "Get the current weather in a city",
+			"parameters": {
+				"type": "object",
+				"properties": {
+					"city": {"type": "string"},
+					"unit": {"type": "string", "enum": ["celsius", "fahrenheit"]}
+				},
+				"required": ["city"]
+			}
+		}
+	}]`), &tools)
+	if err != nil {
+		log.Fatal(err)
+	}
+	n, err := chat.CountTools("gpt-4o", tools)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d tokens\n", n)
+	// Output: 50 tokens
+}
+
+func ExampleEstimateRequest() {
+	req := chat.Request{
+		Model: "gpt-4o",
+		Messages: []chat.Message{{
+			Role:    "user",
+			Content: "What is in this picture?",
+			Images:  []chat.Image{{Width: 1024, Height: 1024, Detail: "high"}},
+		}},
+	}
+	u, err := chat.EstimateRequest(req)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d text + %d image = %d tokens\n", u.Messages, u.Images, u.Total())
+	// Output: 13 text + 765 image = 778 tokens
+}
@@ -0,0 +1,149 @@
+package chat
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif"  // register GIF for DecodeImage
+	_ "image/jpeg" // register JPEG for DecodeImage
+	_ "image/png"  // register PNG for DecodeImage
+	"io"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// An Image is an image attached to a message. Only its size affects the
+// number of tokens it uses.
+type Image struct {
+	Width  int `json:"width"`
+	Height int `json:"height"`
+
+	// Detail is OpenAI's detail level: "low", "high", or "auto".
+	// Auto, the default, is counted as high, which is the most an image
+	// can cost. Claude models ignore Detail.
+	Detail string `json:"detail,omitempty"`
+}
+
+// DecodeImage reads the dimensions of a PNG, JPEG, or GIF image from r.
+// Only the image header is read.
+func DecodeImage(r io.Reader) (Image, error) {
+	cfg, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return Image{}, err
+	}
+	return Image{Width: cfg.Width, Height: cfg.Height}, nil
+}
+
+// OpenImage reads the dimensions of the named PNG, JPEG, or GIF file.
+func OpenImage(name string) (Image, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return Image{}, err
+	}
+	defer f.Close()
+	img, err := DecodeImage(f)
+	if err != nil {
+		return Image{}, fmt.Errorf("%s: %w", name, err)
+	}
+	return img, nil
+}
+
+// ImageTokens returns the number of tokens that the named model uses for
+// img. The model is resolved with bpe.ForModel.
+//
+// For OpenAI models the count follows OpenAI's tile formula: the image is
+// scaled to fit within 2048×2048 and then so its shortest side is at most
+// 768 pixels, and each 512-pixel tile it covers costs a fixed number of
+// tokens on top of a base cost. Low detail images cost only the base.
+//
+// For Claude models the count follows Anthropic's rule of one token per
+// 750 pixels, after scaling the image so its longest side is at most 1568
+// pixels and it costs at most about 1600 tokens.
+func ImageTokens(model string, img Image) (int, error) {
+	m, err := bpe.ForModel(model)
+	if err != nil {
+		return 0, err
+	}
+	return imageTokens(m, img), nil
+}
+
+func imageTokens(m bpe.Model, img Image) int {
+	if m.Encoding == "anthropic" {
+		return anthropicImageTokens(img)
+	}
+	return tileCostFor(m.Name).tokens(img)
+}
+
+// A tileCost is the price of an image for an OpenAI model.
+type tileCost struct {
+	base    int // tokens for every image, and all a low detail image costs
+	perTile int // tokens for each 512-pixel tile of a high detail image
+}
+
+// tileCosts lists, by model name prefix, the image costs of the OpenAI
+// models that differ from defaultTileCost.
+var tileCosts = map[string]tileCost{
+	"gpt-4o-mini": {base: 2833, perTile: 5667},
+	"o1":          {base: 75, perTile: 150},
+	"o3":          {base: 75, perTile: 150},
+}
+
+// defaultTileCost is the image cost of gpt-4o, gpt-4.1, gpt-4-turbo, and
+// gpt-4.5.
+var defaultTileCost = tileCost{base: 85, perTile: 170}
+
+// tileCostFor returns the image cost for the named OpenAI model.
+func tileCostFor(model string) tileCost {
+	model = baseModel(model)
+	c, best := defaultTileCost, ""
+	for prefix, cost := range tileCosts {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			c, best = cost, prefix
+		}
+	}
+	return c
+}
+
+// tokens returns the number of tokens for img.
+func (c tileCost) tokens(img Image) int {
+	if img.Width <= 0 || img.Height <= 0 {
+		return 0
+	}
+	if img.Detail == "low" {
+		return c.base
+	}
+	w, h := float64(img.Width), float64(img.Height)
+	if s := 2048 / max(w, h); s < 1 {
+		w, h = w*s, h*s
+	}
+	if s := 768 / min(w, h); s < 1 {
+		w, h = w*s, h*s
+	}
+	tiles := math.Ceil(w/512) * math.Ceil(h/512)
+	return c.base + c.perTile*int(tiles)
+}
+
+// Anthropic scales images down to these limits before counting them.
+const (
+	maxImageEdge   = 1568
+	pixelsPerToken = 750
+	maxImagePixels = 1600 * pixelsPerToken
+)
+
+// anthropicImageTokens returns the number of tokens a Claude model uses
+// for img.
+func anthropicImageTokens(img Image) int {
+	if img.Width <= 0 || img.Height <= 0 {
+		return 0
+	}
+	w, h := float64(img.Width), float64(img.Height)
+	if s := maxImageEdge / max(w, h); s < 1 {
+		w, h = w*s, h*s
+	}
+	if s := math.Sqrt(maxImagePixels / (w * h)); s < 1 {
+		w, h = w*s, h*s
+	}
+	return int(math.Ceil(math.Round(w) * math.Round(h) / pixelsPerToken))
+}
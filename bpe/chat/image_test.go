@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestImageTokensOpenAI(t *testing.T) {
+	// Examples from OpenAI's vision guide.
+	tests := []struct {
+		model string
+		img   Image
+		want  int
+	}{
+		{"gpt-4o", Image{Width: 1024, Height: 1024}, 765},
+		{"gpt-4o", Image{Width: 1024, Height: 1024, Detail: "auto"}, 765},
+		{"gpt-4o", Image{Width: 2048, Height: 4096, Detail: "high"}, 1105},
+		{"gpt-4o", Image{Width: 4096, Height: 8192, Detail: "low"}, 85},
+		{"gpt-4o", Image{Width: 512, Height: 512}, 255},
+		{"gpt-4.1", Image{Width: 1024, Height: 1024}, 765},
+		{"gpt-4o-mini", Image{Width: 1024, Height: 1024}, 2833 + 4*5667},
+		{"gpt-4o-mini-2024-07-18", Image{Width: 100, Height: 100, Detail: "low"}, 2833},
+		{"o1", Image{Width: 1024, Height: 1024}, 75 + 4*150},
+		{"gpt-4o", Image{}, 0},
+	}
+	for _, tt := range tests {
+		got, err := ImageTokens(tt.model, tt.img)
+		if err != nil {
+			t.Errorf("ImageTokens(%q, %+v): %v", tt.model, tt.img, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("ImageTokens(%q, %+v) = %d, want %d", tt.model, tt.img, got, tt.want)
+		}
+	}
+}
+
+func TestImageTokensAnthropic(t *testing.T) {
+	// Examples from Anthropic's vision guide.
+	tests := []struct {
+		img  Image
+		want int
+	}{
+		{Image{Width: 200, Height: 200}, 54},
+		{Image{Width: 1000, Height: 1000}, 1334},
+		{Image{Width: 1092, Height: 1092}, 1590},
+		{Image{Width: 1092, Height: 1092, Detail: "low"}, 1590},
+		{Image{Width: 4000, Height: 4000}, 1599}, // scaled to 1095×1095
+		{Image{Width: 8000, Height: 1000}, 410},  // scaled to 1568×196
+	}
+	for _, tt := range tests {
+		got, err := ImageTokens("claude-3-5-sonnet", tt.img)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("ImageTokens(%+v) = %d, want %d", tt.img, got, tt.want)
+		}
+	}
+}
+
+func TestDecodeImage(t *testing.T) {
+	m := image.NewPaletted(image.Rect(0, 0, 300, 200), []color.Color{color.White})
+	encoders := map[string]func(io.Writer, image.Image) error{
+		"png":  png.Encode,
+		"gif":  func(w io.Writer, m image.Image) error { return gif.Encode(w, m, nil) },
+		"jpeg": func(w io.Writer, m image.Image) error { return jpeg.Encode(w, m, nil) },
+	}
+	dir := t.TempDir()
+	for format, encode := range encoders {
+		var buf bytes.Buffer
+		if err := encode(&buf, m); err != nil {
+			t.Fatal(err)
+		}
+		name := filepath.Join(dir, "image."+format)
+		if err := os.WriteFile(name, buf.Bytes(), 0o666); err != nil {
+			t.Fatal(err)
+		}
+		img, err := OpenImage(name)
+		if err != nil {
+			t.Errorf("OpenImage(%s): %v", format, err)
+			continue
+		}
+		if img.Width != 300 || img.Height != 200 {
+			t.Errorf("OpenImage(%s) = %+v, want 300×200", format, img)
+		}
+	}
+
+	if _, err := DecodeImage(bytes.NewReader([]byte("not an image"))); err == nil {
+		t.Error("DecodeImage(text) succeeded, want error")
+	}
+}
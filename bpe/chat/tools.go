@@ -0,0 +1,292 @@
+package chat
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// A Tool is a function that the model may call.
+//
+// A Tool can be unmarshaled from an OpenAI tool definition
+// ({"type": "function", "function": {...}}), a legacy OpenAI function
+// definition, or an Anthropic tool definition with an input_schema.
+type Tool struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Parameters  json.RawMessage `json:"parameters,omitempty"` // JSON Schema of the arguments
+}
+
+// UnmarshalJSON accepts OpenAI tool and function definitions and
+// Anthropic tool definitions.
+func (t *Tool) UnmarshalJSON(data []byte) error {
+	type function struct {
+		Name        string          `json:"name"`
+		Description string          `json:"description"`
+		Parameters  json.RawMessage `json:"parameters"`
+		InputSchema json.RawMessage `json:"input_schema"`
+	}
+	var v struct {
+		function
+		Function *function `json:"function"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	f := v.function
+	if v.Function != nil {
+		f = *v.Function
+	}
+	if f.Name == "" {
+		return errors.New("tool definition has no name")
+	}
+	t.Name, t.Description, t.Parameters = f.Name, f.Description, f.Parameters
+	if t.Parameters == nil {
+		t.Parameters = f.InputSchema
+	}
+	return nil
+}
+
+// CountTools returns the number of prompt tokens that the named model
+// uses for the definitions of tools. The model is resolved with
+// bpe.ForModel.
+//
+// For OpenAI models, the definitions are rendered into the TypeScript-like
+// namespace that the models are known to see in their system prompt.
+// For Claude models, the count is an estimate: the tool definitions as
+// JSON plus the tool use system prompt Anthropic documents for the model.
+//
+// When tools are sent along with messages, use CountRequest, which also
+// accounts for how the definitions combine with the system message.
+func CountTools(model string, tools []Tool) (int, error) {
+	m, enc, err := encoderFor(model)
+	if err != nil {
+		return 0, err
+	}
+	if m.Encoding == "anthropic" {
+		return countAnthropicTools(enc, m.Name, tools)
+	}
+	return countOpenAITools(enc, tools)
+}
+
+// toolsOverhead is the number of tokens OpenAI adds around the rendered
+// tool definitions. It is the "nine per completion" of the heuristics in
+// hmarr/openai-chat-tokens, which were checked there against the prompt
+// tokens reported by the API for gpt-3.5-turbo and gpt-4.
+const toolsOverhead = 9
+
+// countOpenAITools counts the rendered definitions of tools.
+func countOpenAITools(enc bpe.Counter, tools []Tool) (int, error) {
+	if len(tools) == 0 {
+		return 0, nil
+	}
+	text, err := renderTools(tools)
+	if err != nil {
+		return 0, err
+	}
+	return enc.Count(text) + toolsOverhead, nil
+}
+
+// renderTools renders tools the way OpenAI presents function definitions
+// to its models:
+//
+//	namespace functions {
+//
+//	// Get the weather
+//	type get_weather = (_: {
+//	// City name
+//	location: string,
+//	unit?: "c" | "f",
+//	}) => any;
+//
+//	} // namespace functions
+func renderTools(tools []Tool) (string, error) {
+	lines := []string{"namespace functions {", ""}
+	for _, t := range tools {
+		var s schema
+		if len(t.Parameters) > 0 {
+			if err := json.Unmarshal(t.Parameters, &s); err != nil {
+				return "", fmt.Errorf("tool %s: parameters: %w", t.Name, err)
+			}
+		}
+		if t.Description != "" {
+			lines = append(lines, "// "+t.Description)
+		}
+		if len(s.Properties) > 0 {
+			lines = append(lines, "type "+t.Name+" = (_: {")
+			lines = append(lines, s.properties(0))
+			lines = append(lines, "}) => any;")
+		} else {
+			lines = append(lines, "type "+t.Name+" = () => any;")
+		}
+		lines = append(lines, "")
+	}
+	lines = append(lines, "} // namespace functions")
+	return strings.Join(lines, "\n"), nil
+}
+
+// A schema is the subset of JSON Schema that affects the rendering of
+// tool definitions. Properties are kept in their original order.
+type schema struct {
+	Type        string
+	Description string
+	Enum        []json.RawMessage
+	Items       *schema
+	Properties  []property
+	Required    []string
+}
+
+type property struct {
+	name   string
+	schema *schema
+}
+
+func (s *schema) UnmarshalJSON(data []byte) error {
+	var v struct {
+		Type        json.RawMessage   `json:"type"`
+		Description string            `json:"description"`
+		Enum        []json.RawMessage `json:"enum"`
+		Items       *schema           `json:"items"`
+		Properties  json.RawMessage   `json:"properties"`
+		Required    []string          `json:"required"`
+	}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	s.Description, s.Enum, s.Items, s.Required = v.Description, v.Enum, v.Items, v.Required
+
+	// A type may be a list, such as ["string", "null"].
+	var types []string
+	if err := json.Unmarshal(v.Type, &s.Type); err != nil && json.Unmarshal(v.Type, &types) == nil {
+		for _, typ := range types {
+			if typ != "null" {
+				s.Type = typ
+				break
+			}
+		}
+	}
+
+	if len(v.Properties) == 0 || string(v.Properties) == "null" {
+		return nil
+	}
+	d := json.NewDecoder(bytes.NewReader(v.Properties))
+	if tok, err := d.Token(); err != nil || tok != json.Delim('{') {
+		return errors.New("properties is not an object")
+	}
+	for d.More() {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		p := property{name: tok.(string), schema: new(schema)}
+		if err := d.Decode(p.schema); err != nil {
+			return fmt.Errorf("property %s: %w", p.name, err)
+		}
+		s.Properties = append(s.Properties, p)
+	}
+	return nil
+}
+
+// properties renders the properties of an object type, one per line,
+// indented by indent spaces. Descriptions of deeply nested properties
+// are left out.
+func (s *schema) properties(indent int) string {
+	var lines []string
+	for _, p := range s.Properties {
+		if p.schema.Description != "" && indent < 2 {
+			lines = append(lines, "// "+p.schema.Description)
+		}
+		opt := "?"
+		for _, r := range s.Required {
+			if r == p.name {
+				opt = ""
+			}
+		}
+		lines = append(lines, p.name+opt+": "+p.schema.format(indent)+",")
+	}
+	for i := range lines {
+		lines[i] = strings.Repeat(" ", indent) + lines[i]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// format renders s as a TypeScript type.
+func (s *schema) format(indent int) string {
+	switch s.Type {
+	case "string", "number", "integer":
+		if len(s.Enum) > 0 {
+			return s.enum()
+		}
+		if s.Type == "string" {
+			return "string"
+		}
+		return "number"
+	case "boolean", "null":
+		return s.Type
+	case "object":
+		return "{\n" + s.properties(indent+2) + "\n}"
+	case "array":
+		if s.Items != nil {
+			return s.Items.format(indent) + "[]"
+		}
+		return "any[]"
+	}
+	if len(s.Enum) > 0 {
+		return s.enum()
+	}
+	return "any" // untyped, or of a type that has no TypeScript equivalent
+}
+
+// enum renders the values of s as a union of literal types.
+func (s *schema) enum() string {
+	values := make([]string, len(s.Enum))
+	for i, v := range s.Enum {
+		values[i] = string(v)
+	}
+	return strings.Join(values, " | ")
+}
+
+// anthropicToolPrompts lists, by model name prefix, the number of tokens
+// in the system prompt that Claude models get when tools are provided and
+// tool_choice is auto or none, as documented by Anthropic.
+var anthropicToolPrompts = map[string]int{
+	"claude-3-opus":              530,
+	"claude-3-sonnet":            159,
+	"claude-3-haiku":             264,
+	"claude-3-5-haiku":           264,
+	"claude-3-5-sonnet-20240620": 294,
+}
+
+// defaultAnthropicToolPrompt is the tool use system prompt size of
+// Claude 3.5 Sonnet (October 2024) and later models.
+const defaultAnthropicToolPrompt = 346
+
+// countAnthropicTools estimates the tokens used by tools for a Claude model.
+func countAnthropicTools(enc bpe.Counter, model string, tools []Tool) (int, error) {
+	if len(tools) == 0 {
+		return 0, nil
+	}
+	model = baseModel(model)
+	n, best := defaultAnthropicToolPrompt, ""
+	for prefix, size := range anthropicToolPrompts {
+		if strings.HasPrefix(model, prefix) && len(prefix) > len(best) {
+			n, best = size, prefix
+		}
+	}
+	for _, t := range tools {
+		b, err := json.Marshal(struct {
+			Name        string          `json:"name"`
+			Description string          `json:"description,omitempty"`
+			InputSchema json.RawMessage `json:"input_schema,omitempty"`
+		}{t.Name, t.Description, t.Parameters})
+		if err != nil {
+			return 0, fmt.Errorf("tool %s: %w", t.Name, err)
+		}
+		n += enc.Count(string(b))
+	}
+	return n, nil
+}
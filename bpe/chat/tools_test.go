@@ -0,0 +1,189 @@
+package chat
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+func TestToolUnmarshalJSON(t *testing.T) {
+	want := Tool{Name: "get_weather", Description: "Get the weather", Parameters: json.RawMessage(`{"type":"object"}`)}
+	for _, def := range []string{
+		`{"type": "function", "function": {"name": "get_weather", "description": "Get the weather", "parameters": {"type":"object"}}}`,
+		`{"name": "get_weather", "description": "Get the weather", "parameters": {"type":"object"}}`,
+		`{"name": "get_weather", "description": "Get the weather", "input_schema": {"type":"object"}}`,
+	} {
+		var got Tool
+		if err := json.Unmarshal([]byte(def), &got); err != nil {
+			t.Errorf("Unmarshal(%s): %v", def, err)
+			continue
+		}
+		if got.Name != want.Name || got.Description != want.Description || string(got.Parameters) != string(want.Parameters) {
+			t.Errorf("Unmarshal(%s) = %+v, want %+v", def, got, want)
+		}
+	}
+
+	var tool Tool
+	if err := json.Unmarshal([]byte(`{"description": "nameless"}`), &tool); err == nil {
+		t.Error("Unmarshal of tool without name succeeded, want error")
+	}
+}
+
+func TestRenderTools(t *testing.T) {
+	var tools []Tool
+	err := json.Unmarshal([]byte(`[{
+		"type": "function",
+		"function": {
+			"name": "search",
+			"description": "Search the catalog",
+			"parameters": {
+				"type": "object",
+				"properties": {
+					"query": {"type": "string", "description": "Search terms"},
+					"sort": {"type": "string", "enum": ["price", "rating"]},
+					"limit": {"type": ["integer", "null"]},
+					"tags": {"type": "array", "items": {"type": "string"}},
+					"extra": {"description": "Anything else"},
+					"mode": {"enum": ["fast", "slow"]},
+					"filter": {
+						"type": "object",
+						"description": "Filters to apply",
+						"properties": {
+							"in_stock": {"type": "boolean", "description": "Only items in stock"},
+							"max_price": {"type": "number"}
+						},
+						"required": ["in_stock"]
+					}
+				},
+				"required": ["query"]
+			}
+		}
+	}, {
+		"name": "ping"
+	}]`), &tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := renderTools(tools)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `namespace functions {
+
+// Search the catalog
+type search = (_: {
+// Search terms
+query: string,
+sort?: "price" | "rating",
+limit?: number,
+tags?: string[],
+// Anything else
+extra?: any,
+mode?: "fast" | "slow",
+// Filters to apply
+filter?: {
+  in_stock: boolean,
+  max_price?: number,
+},
+}) => any;
+
+type ping = () => any;
+
+} // namespace functions`
+	if got != want {
+		t.Errorf("renderTools:\n%s\nwant:\n%s", got, want)
+	}
+
+	_, err = renderTools([]Tool{{Name: "bad", Parameters: json.RawMessage(`{"properties": []}`)}})
+	if err == nil || !strings.Contains(err.Error(), "bad") {
+		t.Errorf("renderTools with invalid schema: err = %v, want error naming the tool", err)
+	}
+}
+
+func TestCountRequestOpenAITools(t *testing.T) {
+	// Prompt token counts reported by the API for gpt-3.5-turbo.
+	tests := []struct {
+		tool string
+		want int
+	}{
+		{`{"name": "foo", "parameters": {"type": "object", "properties": {}}}`, 31},
+		{`{"name": "foo", "description": "Do a foo", "parameters": {"type": "object", "properties": {}}}`, 36},
+		{`{"name": "bing_bong", "description": "Do a bing bong", "parameters": {"type": "object", "properties": {"foo": {"type": "string"}}}}`, 49},
+	}
+	for _, tt := range tests {
+		var tool Tool
+		if err := json.Unmarshal([]byte(tt.tool), &tool); err != nil {
+			t.Fatal(err)
+		}
+		req := Request{
+			Model:    "gpt-3.5-turbo",
+			Messages: []Message{{Role: "user", Content: "hello"}},
+			Tools:    []Tool{tool},
+		}
+		got, err := CountRequest(req)
+		if err != nil {
+			t.Errorf("CountRequest(%s): %v", tt.tool, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CountRequest(%s) = %d, want %d", tt.tool, got, tt.want)
+		}
+	}
+}
+
+func TestCountRequestSystemWithTools(t *testing.T) {
+	tools := []Tool{{Name: "foo"}}
+	messages := []Message{
+		{Role: "system", Content: "Be brief."},
+		{Role: "user", Content: "hello"},
+	}
+	got, err := CountRequest(Request{Model: "gpt-4o", Messages: messages, Tools: tools})
+	if err != nil {
+		t.Fatal(err)
+	}
+	enc, _ := bpe.NewEncoder("o200k_base")
+	rendered, _ := renderTools(tools)
+	// The system message gains a newline and shares framing with the tools.
+	want := replyPrimer +
+		enc.Count(rendered) + toolsOverhead - 4 +
+		3 + enc.Count("system") + enc.Count("Be brief.\n") +
+		3 + enc.Count("user") + enc.Count("hello")
+	if got != want {
+		t.Errorf("CountRequest = %d, want %d", got, want)
+	}
+}
+
+func TestCountToolsAnthropic(t *testing.T) {
+	enc, err := bpe.NewEncoder("anthropic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tool := Tool{Name: "get_weather", Description: "Get the weather", Parameters: json.RawMessage(`{"type": "object"}`)}
+	definition := enc.Count(`{"name":"get_weather","description":"Get the weather","input_schema":{"type":"object"}}`)
+	tests := []struct {
+		model string
+		want  int
+	}{
+		{"claude-3-opus-20240229", 530 + definition},
+		{"claude-3-haiku-20240307", 264 + definition},
+		{"claude-3-5-sonnet-20240620", 294 + definition},
+		{"claude-3-5-sonnet-20241022", 346 + definition},
+		{"claude-sonnet-4-5", 346 + definition},
+	}
+	for _, tt := range tests {
+		got, err := CountTools(tt.model, []Tool{tool})
+		if err != nil {
+			t.Errorf("CountTools(%q): %v", tt.model, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("CountTools(%q) = %d, want %d", tt.model, got, tt.want)
+		}
+	}
+
+	if got, err := CountTools("claude-3-opus", nil); got != 0 || err != nil {
+		t.Errorf("CountTools(no tools) = %d, %v, want 0, nil", got, err)
+	}
+}
@@ -12,11 +12,30 @@
 //	}
 //	count := enc.Count("Hello, world!")
 //
+// Some encoders support more than counting and encoding. Optional
+// capabilities are exposed as interfaces that can be discovered with a
+// type assertion; for example, encoders that can turn token IDs back into
+// text implement Decoder.
+//
 // Streaming usage:
 //
 //	w, _ := bpe.NewWriter("anthropic")
 //	io.Copy(w, reader)
 //	count := w.Count()
 //
-// Supported encodings: anthropic, claude, o200k_base, cl100k_base, p50k_base, r50k_base.
+// A Writer tokenizes its input incrementally and holds only the trailing,
+// still ambiguous text in memory, so it can count large inputs in memory
+// bounded by their longest pre-tokenization chunk.
+//
+// TruncateHead, TruncateTail, and TruncateMiddle cut text down to a token
+// budget without splitting UTF-8 sequences, and report the exact count of
+// the result.
+//
+// Built-in encodings: anthropic, claude, o200k_base, cl100k_base, p50k_base, r50k_base.
+// Other packages can add encodings with Register, much as database/sql
+// drivers register themselves; Encodings lists everything available.
+//
+// ForModel maps a model name such as "gpt-4o-2024-08-06" to its encoding.
+// Claude 3 and later models use an unpublished tokenizer, so their counts
+// are approximations based on the anthropic encoding.
 package bpe
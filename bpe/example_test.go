@@ -87,3 +87,88 @@ func ExampleCountReader_file() {
 	fmt.Printf("%d tokens\n", count)
 	// Output: 9 tokens
 }
+
+func ExampleDecoder() {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	tokens := enc.Encode("Hello, world!")
+	if dec, ok := enc.(bpe.Decoder); ok {
+		text, err := dec.Decode(tokens[:2])
+		if err != nil {
+			log.Fatal(err)
+		}
+		fmt.Printf("%q\n", text)
+	}
+	// Output: "Hello,"
+}
+
+// byteEncoder is a toy encoding with one token per byte.
+type byteEncoder struct{}
+
+func (byteEncoder) Count(text string) int { return len(text) }
+
+func (byteEncoder) Encode(text string) []int {
+	tokens := make([]int, len(text))
+	for i := range len(text) {
+		tokens[i] = int(text[i])
+	}
+	return tokens
+}
+
+func (byteEncoder) EncodeWithOffsets(text string) ([]int, [][2]int) {
+	offsets := make([][2]int, len(text))
+	for i := range offsets {
+		offsets[i] = [2]int{i, i + 1}
+	}
+	return byteEncoder{}.Encode(text), offsets
+}
+
+func ExampleRegister() {
+	// Typically called from the init function of the package providing the encoding.
+	bpe.Register("bytes", func() (bpe.Encoder, error) {
+		return byteEncoder{}, nil
+	}, "raw")
+
+	enc, err := bpe.NewEncoder("raw")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Printf("%d tokens\n", enc.Count("Hello, world!"))
+	// Output: 13 tokens
+}
+
+func ExampleForModel() {
+	m, err := bpe.ForModel("gpt-4o-2024-08-06")
+	if err != nil {
+		log.Fatal(err)
+	}
+	enc, err := bpe.NewEncoder(m.Encoding)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	fmt.Printf("%s: %d tokens\n", m.Encoding, enc.Count("Hello, world!"))
+	// Output: o200k_base: 4 tokens
+}
+
+func ExampleTruncateHead() {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text := "The quick brown fox jumps over the lazy dog"
+	head, n := bpe.TruncateHead(enc, text, 3)
+	fmt.Printf("%q (%d tokens)\n", head, n)
+	tail, n := bpe.TruncateTail(enc, text, 3)
+	fmt.Printf("%q (%d tokens)\n", tail, n)
+	middle, n := bpe.TruncateMiddle(enc, text, 5, "…")
+	fmt.Printf("%q (%d tokens)\n", middle, n)
+	// Output:
+	// "The quick brown" (3 tokens)
+	// " the lazy dog" (3 tokens)
+	// "The quick… lazy dog" (5 tokens)
+}
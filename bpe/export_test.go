@@ -0,0 +1,15 @@
+package bpe
+
+// Unregister removes an encoding added with Register, along with its
+// aliases, so that tests that register encodings can be run more than
+// once.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.factories, name)
+	for alias, n := range registry.aliases {
+		if n == name {
+			delete(registry.aliases, alias)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package bpe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// A Model describes the encoding used to tokenize text for a model.
+type Model struct {
+	Name     string // model name, as passed to ForModel
+	Encoding string // encoding name, for NewEncoder
+
+	// Approximate reports that Encoding only approximates the model's
+	// real tokenizer, so counts are estimates. This is the case for
+	// Claude 3 and later models, whose tokenizer is not public.
+	Approximate bool
+}
+
+type modelEncoding struct {
+	encoding    string
+	approximate bool
+}
+
+var (
+	o200k     = modelEncoding{encoding: "o200k_base"}
+	cl100k    = modelEncoding{encoding: "cl100k_base"}
+	p50k      = modelEncoding{encoding: "p50k_base"}
+	r50k      = modelEncoding{encoding: "r50k_base"}
+	claude    = modelEncoding{encoding: "anthropic"}
+	newClaude = modelEncoding{encoding: "anthropic", approximate: true}
+)
+
+// modelEncodings maps model names to encodings, following tiktoken's
+// MODEL_TO_ENCODING table.
+var modelEncodings = map[string]modelEncoding{
+	// chat
+	"gpt-5":             o200k,
+	"gpt-4.5":           o200k,
+	"gpt-4.1":           o200k,
+	"gpt-4o":            o200k,
+	"chatgpt-4o-latest": o200k,
+	"o1":                o200k,
+	"o3":                o200k,
+	"o4-mini":           o200k,
+	"gpt-4":             cl100k,
+	"gpt-3.5-turbo":     cl100k,
+	"gpt-3.5":           cl100k,
+	"gpt-35-turbo":      cl100k, // Azure deployment name
+	// base
+	"davinci-002": cl100k,
+	"babbage-002": cl100k,
+	// embeddings
+	"text-embedding-ada-002": cl100k,
+	"text-embedding-3-small": cl100k,
+	"text-embedding-3-large": cl100k,
+	// DEPRECATED MODELS
+	// text (DEPRECATED)
+	"text-davinci-003": p50k,
+	"text-davinci-002": p50k,
+	"text-davinci-001": r50k,
+	"text-curie-001":   r50k,
+	"text-babbage-001": r50k,
+	"text-ada-001":     r50k,
+	"davinci":          r50k,
+	"curie":            r50k,
+	"babbage":          r50k,
+	"ada":              r50k,
+	// code (DEPRECATED)
+	"code-davinci-002": p50k,
+	"code-davinci-001": p50k,
+	"code-cushman-002": p50k,
+	"code-cushman-001": p50k,
+	"davinci-codex":    p50k,
+	"cushman-codex":    p50k,
+	// edit (DEPRECATED)
+	"text-davinci-edit-001": p50k,
+	"code-davinci-edit-001": p50k,
+	// old embeddings (DEPRECATED)
+	"text-similarity-davinci-001":  r50k,
+	"text-similarity-curie-001":    r50k,
+	"text-similarity-babbage-001":  r50k,
+	"text-similarity-ada-001":      r50k,
+	"text-search-davinci-doc-001":  r50k,
+	"text-search-curie-doc-001":    r50k,
+	"text-search-babbage-doc-001":  r50k,
+	"text-search-ada-doc-001":      r50k,
+	"code-search-babbage-code-001": r50k,
+	"code-search-ada-code-001":     r50k,
+	// open source
+	"gpt2": r50k,
+}
+
+// modelPrefixEncodings maps model name prefixes to encodings, for dated
+// snapshots and fine-tuned models. It follows tiktoken's
+// MODEL_PREFIX_TO_ENCODING table, extended with Claude models.
+var modelPrefixEncodings = map[string]modelEncoding{
+	// chat
+	"gpt-5-":         o200k,
+	"gpt-4.5-":       o200k,
+	"gpt-4.1-":       o200k,
+	"gpt-4o-":        o200k, // e.g., gpt-4o-2024-05-13
+	"chatgpt-4o-":    o200k,
+	"o1-":            o200k,
+	"o3-":            o200k,
+	"o4-mini-":       o200k,
+	"gpt-4-":         cl100k, // e.g., gpt-4-0314, gpt-4-turbo
+	"gpt-3.5-turbo-": cl100k, // e.g., gpt-3.5-turbo-0301, -0401, etc.
+	"gpt-35-turbo-":  cl100k, // Azure deployment name
+	// fine-tuned
+	"ft:gpt-4o":        o200k,
+	"ft:gpt-4":         cl100k,
+	"ft:gpt-3.5-turbo": cl100k,
+	"ft:davinci-002":   cl100k,
+	"ft:babbage-002":   cl100k,
+	// Claude
+	"claude-instant-1": claude,
+	"claude-1":         claude,
+	"claude-2":         claude,
+	"claude-":          newClaude, // Claude 3 and later
+}
+
+// ForModel returns the encoding used by the named model, like tiktoken's
+// encoding_for_model. Model names are matched exactly first, then by the
+// longest known prefix, so dated snapshots such as "gpt-4o-2024-08-06" and
+// fine-tuned models resolve to their base model's encoding. A provider
+// prefix such as "openai/" is ignored.
+//
+// For Claude 3 and later models the result is marked Approximate.
+func ForModel(model string) (Model, error) {
+	name := model
+	if i := strings.LastIndexByte(name, '/'); i >= 0 {
+		name = name[i+1:]
+	}
+
+	m, ok := modelEncodings[name]
+	if !ok {
+		best := ""
+		for prefix, enc := range modelPrefixEncodings {
+			if strings.HasPrefix(name, prefix) && len(prefix) > len(best) {
+				best, m, ok = prefix, enc, true
+			}
+		}
+	}
+	if !ok {
+		return Model{}, fmt.Errorf("unknown model %q", model)
+	}
+	return Model{Name: model, Encoding: m.encoding, Approximate: m.approximate}, nil
+}
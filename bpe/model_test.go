@@ -0,0 +1,50 @@
+package bpe
+
+import "testing"
+
+func TestForModel(t *testing.T) {
+	tests := []struct {
+		model       string
+		encoding    string
+		approximate bool
+	}{
+		{"gpt-4o", "o200k_base", false},
+		{"gpt-4o-2024-08-06", "o200k_base", false},
+		{"gpt-4o-mini", "o200k_base", false},
+		{"gpt-4.1-nano", "o200k_base", false},
+		{"o1-preview", "o200k_base", false},
+		{"openai/gpt-4o", "o200k_base", false},
+		{"ft:gpt-4o-mini:org::abc123", "o200k_base", false},
+		{"gpt-4", "cl100k_base", false},
+		{"gpt-4-turbo-2024-04-09", "cl100k_base", false},
+		{"gpt-3.5-turbo-0125", "cl100k_base", false},
+		{"ft:gpt-3.5-turbo:org::abc123", "cl100k_base", false},
+		{"text-embedding-3-small", "cl100k_base", false},
+		{"text-davinci-003", "p50k_base", false},
+		{"davinci", "r50k_base", false},
+		{"claude-2.1", "anthropic", false},
+		{"claude-instant-1.2", "anthropic", false},
+		{"claude-3-5-sonnet-20241022", "anthropic", true},
+		{"claude-sonnet-4-5", "anthropic", true},
+		{"anthropic/claude-3-opus", "anthropic", true},
+	}
+	for _, tt := range tests {
+		m, err := ForModel(tt.model)
+		if err != nil {
+			t.Errorf("ForModel(%q): %v", tt.model, err)
+			continue
+		}
+		if m.Name != tt.model || m.Encoding != tt.encoding || m.Approximate != tt.approximate {
+			t.Errorf("ForModel(%q) = %+v, want encoding %s, approximate %v", tt.model, m, tt.encoding, tt.approximate)
+		}
+		if _, err := NewEncoder(m.Encoding); err != nil {
+			t.Errorf("NewEncoder(%q) for model %q: %v", m.Encoding, tt.model, err)
+		}
+	}
+
+	for _, model := range []string{"", "llama-3", "gpt-4o/", "claude"} {
+		if m, err := ForModel(model); err == nil {
+			t.Errorf("ForModel(%q) = %+v, want error", model, m)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package bpe
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/tmc/tokencount/anthropictokenizer"
+	"github.com/tmc/tokencount/openaitokenizer"
+)
+
+// DefaultEncoding is the encoding used when NewEncoder is given an empty name.
+const DefaultEncoding = "o200k_base"
+
+// A Factory returns an Encoder for a registered encoding.
+// It is called each time the encoding is requested by name.
+type Factory func() (Encoder, error)
+
+var registry struct {
+	sync.RWMutex
+	factories map[string]Factory // by name
+	aliases   map[string]string  // alias to name
+}
+
+func init() {
+	Register("anthropic", func() (Encoder, error) {
+		counter, err := anthropictokenizer.NewCounter()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create anthropic tokenizer: %w", err)
+		}
+		return counter, nil
+	}, "claude")
+	for _, name := range []string{"o200k_base", "cl100k_base", "p50k_base", "r50k_base"} {
+		Register(name, func() (Encoder, error) {
+			return openaitokenizer.NewEncoder(name)
+		})
+	}
+}
+
+// Register makes an encoding available to NewEncoder by the provided name
+// and any aliases. Packages providing an encoding typically call it from an
+// init function, so that importing them for side effects is enough:
+//
+//	import _ "example.com/mytokenizer"
+//
+// If Register is called twice with the same name or alias, or if factory
+// is nil, it panics.
+func Register(name string, factory Factory, aliases ...string) {
+	if factory == nil {
+		panic("bpe: Register factory is nil")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if registry.factories == nil {
+		registry.factories = make(map[string]Factory)
+		registry.aliases = make(map[string]string)
+	}
+	for _, n := range append([]string{name}, aliases...) {
+		if _, dup := registry.factories[n]; dup {
+			panic("bpe: Register called twice for encoding " + n)
+		}
+		if _, dup := registry.aliases[n]; dup {
+			panic("bpe: Register called twice for encoding " + n)
+		}
+	}
+	registry.factories[name] = factory
+	for _, alias := range aliases {
+		registry.aliases[alias] = name
+	}
+}
+
+// Encodings returns a sorted list of the names of the available encodings,
+// including custom vocabularies added with openaitokenizer.Register.
+// Aliases are not included.
+func Encodings() []string {
+	registry.RLock()
+	names := make([]string, 0, len(registry.factories))
+	for name := range registry.factories {
+		names = append(names, name)
+	}
+	registry.RUnlock()
+	for _, name := range openaitokenizer.Encodings() {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// lookup returns the factory for the named encoding or alias.
+func lookup(name string) (Factory, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	if alias, ok := registry.aliases[name]; ok {
+		name = alias
+	}
+	f, ok := registry.factories[name]
+	return f, ok
+}
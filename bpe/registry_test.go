@@ -0,0 +1,73 @@
+package bpe
+
+import (
+	"slices"
+	"strings"
+	"testing"
+)
+
+// runeEncoder is a toy encoding with one token per rune.
+type runeEncoder struct{}
+
+func (runeEncoder) Count(text string) int { return len([]rune(text)) }
+
+func (runeEncoder) Encode(text string) []int {
+	tokens, _ := runeEncoder{}.EncodeWithOffsets(text)
+	return tokens
+}
+
+func (runeEncoder) EncodeWithOffsets(text string) ([]int, [][2]int) {
+	var tokens []int
+	var offsets [][2]int
+	for i, r := range text {
+		tokens = append(tokens, int(r))
+		offsets = append(offsets, [2]int{i, i + len(string(r))})
+	}
+	return tokens, offsets
+}
+
+func TestRegister(t *testing.T) {
+	Register("test_runes", func() (Encoder, error) { return runeEncoder{}, nil }, "test_runes_alias")
+	t.Cleanup(func() { Unregister("test_runes") })
+
+	for _, name := range []string{"test_runes", "test_runes_alias"} {
+		enc, err := NewEncoder(name)
+		if err != nil {
+			t.Fatalf("NewEncoder(%q): %v", name, err)
+		}
+		if n := enc.Count("héllo"); n != 5 {
+			t.Errorf("NewEncoder(%q).Count = %d, want 5", name, n)
+		}
+	}
+
+	names := Encodings()
+	if !slices.Contains(names, "test_runes") {
+		t.Errorf("Encodings() = %v, want test_runes included", names)
+	}
+	if slices.Contains(names, "test_runes_alias") {
+		t.Errorf("Encodings() = %v, want aliases excluded", names)
+	}
+	if !slices.IsSorted(names) {
+		t.Errorf("Encodings() = %v, want sorted", names)
+	}
+
+	for _, name := range []string{"test_runes", "claude", "o200k_base"} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), name) {
+					t.Errorf("Register(%q) panic = %v, want duplicate registration panic", name, r)
+				}
+			}()
+			Register(name, func() (Encoder, error) { return runeEncoder{}, nil })
+		}()
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	names := Encodings()
+	for _, want := range []string{"anthropic", "o200k_base", "cl100k_base", "p50k_base", "r50k_base"} {
+		if !slices.Contains(names, want) {
+			t.Errorf("Encodings() = %v, want %s included", names, want)
+		}
+	}
+}
@@ -0,0 +1,29 @@
+// Package splitter splits documents into chunks that fit a token budget,
+// as used for retrieval-augmented generation.
+//
+// Basic usage:
+//
+//	enc, err := bpe.NewEncoder("o200k_base")
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	s, err := splitter.New(enc, 512, 64)
+//	if err != nil {
+//	    log.Fatal(err)
+//	}
+//	for _, c := range s.Split(text) {
+//	    fmt.Println(c.Start, c.End, c.Tokens)
+//	}
+//
+// Chunks are split at paragraph breaks where possible, then at the ends
+// of sentences, then between words, and only as a last resort between
+// tokens. Each chunk records its byte offsets in the document and its
+// exact token count, and consecutive chunks can overlap by a number of
+// tokens so that no passage loses its context.
+//
+// SplitMarkdown and SplitGo follow the structure of Markdown documents
+// and Go source files, and record in each chunk's Context the headings or
+// declaration it belongs to. Other structure-aware splitters can divide a
+// document into Sections and pass them to SplitSections, which applies
+// the same token budget logic.
+package splitter
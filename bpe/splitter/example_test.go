@@ -0,0 +1,47 @@
+package splitter_test
+
+import (
+	"fmt"
+	"log"
+
+	"github.com/tmc/tokencount/bpe"
+	"github.com/tmc/tokencount/bpe/splitter"
+)
+
+func ExampleSplitter_Split() {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := splitter.New(enc, 12, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text := "The first paragraph is short.\n\nThe second paragraph is a little longer than the first one.\n"
+	for _, c := range s.Split(text) {
+		fmt.Printf("[%d:%d] %d tokens: %q\n", c.Start, c.End, c.Tokens, c.Text)
+	}
+	// Output:
+	// [0:31] 6 tokens: "The first paragraph is short.\n\n"
+	// [31:91] 12 tokens: "The second paragraph is a little longer than the first one.\n"
+}
+
+func ExampleSplitter_SplitMarkdown() {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+	s, err := splitter.New(enc, 100, 0)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text := "# Guide\n\nIntroduction.\n\n## Install\n\nRun go install.\n"
+	for _, c := range s.SplitMarkdown(text) {
+		fmt.Printf("%q %q\n", c.Context, c.Text)
+	}
+	// Output:
+	// ["Guide"] "# Guide\n\nIntroduction.\n\n"
+	// ["Guide" "Install"] "## Install\n\nRun go install.\n"
+}
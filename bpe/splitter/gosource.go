@@ -0,0 +1,95 @@
+package splitter
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+)
+
+// SplitGo is like Split, but follows the structure of Go source code.
+// Each top-level declaration, with its doc comment, is split on its own,
+// and its chunks carry as their Context the package clause and a summary
+// of the declaration, such as "func (*Writer) Write". Declarations that
+// do not fit are split at blank lines and then between lines.
+//
+// SplitGo returns an error if src cannot be parsed.
+func (s *Splitter) SplitGo(src string) ([]Chunk, error) {
+	sections, err := GoSections(src)
+	if err != nil {
+		return nil, err
+	}
+	return s.SplitSections(src, sections), nil
+}
+
+// GoSections returns the sections of a Go source file: one for the package
+// clause and imports, and one for each other top-level declaration.
+func GoSections(src string) ([]Section, error) {
+	fset := token.NewFileSet()
+	f, err := parser.ParseFile(fset, "", src, parser.ParseComments|parser.SkipObjectResolution)
+	if err != nil {
+		return nil, err
+	}
+	file := fset.File(f.Package)
+	pkg := "package " + f.Name.Name
+
+	sections := []Section{{Start: 0, Context: []string{pkg}, Code: true}}
+	for _, decl := range f.Decls {
+		if d, ok := decl.(*ast.GenDecl); ok && d.Tok == token.IMPORT {
+			continue
+		}
+		pos := decl.Pos()
+		if doc := declDoc(decl); doc != nil {
+			pos = doc.Pos()
+		}
+		// Start at the beginning of the line.
+		start := file.Offset(pos)
+		start = strings.LastIndexByte(src[:start], '\n') + 1
+
+		sections[len(sections)-1].End = start
+		sections = append(sections, Section{Start: start, Context: []string{pkg, describe(decl)}, Code: true})
+	}
+	sections[len(sections)-1].End = len(src)
+	return sections, nil
+}
+
+// declDoc returns the doc comment of decl, if any.
+func declDoc(decl ast.Decl) *ast.CommentGroup {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		return d.Doc
+	case *ast.GenDecl:
+		return d.Doc
+	}
+	return nil
+}
+
+// describe summarizes decl, as in "func (*T) Name", "type T", or
+// "const (A, B)".
+func describe(decl ast.Decl) string {
+	switch d := decl.(type) {
+	case *ast.FuncDecl:
+		if d.Recv != nil && len(d.Recv.List) > 0 {
+			return "func (" + types.ExprString(d.Recv.List[0].Type) + ") " + d.Name.Name
+		}
+		return "func " + d.Name.Name
+	case *ast.GenDecl:
+		var names []string
+		for _, spec := range d.Specs {
+			switch s := spec.(type) {
+			case *ast.TypeSpec:
+				names = append(names, s.Name.Name)
+			case *ast.ValueSpec:
+				for _, name := range s.Names {
+					names = append(names, name.Name)
+				}
+			}
+		}
+		if d.Lparen.IsValid() {
+			return d.Tok.String() + " (" + strings.Join(names, ", ") + ")"
+		}
+		return d.Tok.String() + " " + strings.Join(names, ", ")
+	}
+	return ""
+}
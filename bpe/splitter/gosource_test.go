@@ -0,0 +1,89 @@
+package splitter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const goSource = `// Package demo is a demo.
+package demo
+
+import "fmt"
+
+// Limits.
+const (
+	Min = 1
+	Max = 10
+)
+
+var verbose bool
+
+// A Point is a point.
+type Point struct{ X, Y int }
+
+// String formats p.
+func (p *Point) String() string {
+	return fmt.Sprintf("(%d, %d)", p.X, p.Y)
+}
+
+func main() {
+	fmt.Println(&Point{1, 2})
+}
+`
+
+func TestGoSections(t *testing.T) {
+	sections, err := GoSections(goSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	type section struct {
+		first   string
+		context []string
+	}
+	var got []section
+	end := 0
+	for _, sec := range sections {
+		if sec.Start != end || !sec.Code {
+			t.Errorf("section %+v does not continue from %d as code", sec, end)
+		}
+		end = sec.End
+		first, _, _ := strings.Cut(goSource[sec.Start:sec.End], "\n")
+		got = append(got, section{first, sec.Context})
+	}
+	if end != len(goSource) {
+		t.Errorf("sections end at %d, want %d", end, len(goSource))
+	}
+	want := []section{
+		{"// Package demo is a demo.", []string{"package demo"}},
+		{"// Limits.", []string{"package demo", "const (Min, Max)"}},
+		{"var verbose bool", []string{"package demo", "var verbose"}},
+		{"// A Point is a point.", []string{"package demo", "type Point"}},
+		{"// String formats p.", []string{"package demo", "func (*Point) String"}},
+		{"func main() {", []string{"package demo", "func main"}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GoSections:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestSplitGo(t *testing.T) {
+	s, enc := newSplitter(t, "o200k_base", 12, 0)
+	chunks, err := s.SplitGo(goSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkChunks(t, enc, goSource, 12, 0, chunks)
+	for _, c := range chunks {
+		// Only lines that do not fit on their own are split.
+		lineStart := strings.LastIndexByte(goSource[:c.End], '\n') + 1
+		lineEnd := c.End + strings.IndexByte(goSource[c.End:], '\n') + 1
+		if !strings.HasSuffix(c.Text, "\n") && enc.Count(goSource[lineStart:lineEnd]) <= 12 {
+			t.Errorf("chunk %q in %v does not end at a line end", c.Text, c.Context)
+		}
+	}
+
+	if _, err := s.SplitGo("package"); err == nil {
+		t.Error("SplitGo(invalid source) succeeded, want error")
+	}
+}
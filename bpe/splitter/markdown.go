@@ -0,0 +1,97 @@
+package splitter
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	atxHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	setextHeading = regexp.MustCompile(`^ {0,3}(=+|-+)[ \t]*$`)
+	fence         = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})")
+)
+
+// SplitMarkdown is like Split, but follows the structure of a Markdown
+// document. Chunks do not cross headings, and each carries as its Context
+// the titles of the headings it is under, from the top level down.
+// Fenced code blocks are kept whole if they fit, and otherwise split
+// between lines rather than at paragraphs and sentences.
+func (s *Splitter) SplitMarkdown(text string) []Chunk {
+	return s.SplitSections(text, MarkdownSections(text))
+}
+
+// MarkdownSections returns the sections of a Markdown document: a section
+// for each heading and the text up to the next one, with the fenced code
+// blocks in it as sections of their own.
+func MarkdownSections(text string) []Section {
+	type heading struct {
+		level int
+		title string
+	}
+	var (
+		sections []Section
+		headings []heading // path to the current heading
+		context  []string
+		start    int    // start of the current section
+		fenced   string // opening fence of the current code block
+		para     = -1   // start of the current paragraph line, for setext headings
+	)
+	cut := func(end int, code bool) {
+		if end > start {
+			sections = append(sections, Section{Start: start, End: end, Context: context, Code: code})
+		}
+		start = end
+	}
+	enter := func(pos, level int, title string) {
+		cut(pos, false)
+		for len(headings) > 0 && headings[len(headings)-1].level >= level {
+			headings = headings[:len(headings)-1]
+		}
+		headings = append(headings, heading{level, title})
+		context = make([]string, len(headings))
+		for i, h := range headings {
+			context[i] = h.title
+		}
+	}
+
+	for pos := 0; pos < len(text); {
+		end := strings.IndexByte(text[pos:], '\n') + 1
+		if end == 0 {
+			end = len(text) - pos
+		}
+		line := strings.TrimRight(text[pos:pos+end], "\r\n")
+
+		switch {
+		case fenced != "":
+			trimmed := strings.TrimSpace(line)
+			if strings.HasPrefix(trimmed, fenced) && strings.Trim(trimmed, fenced[:1]) == "" {
+				fenced = ""
+				cut(pos+end, true)
+			}
+		case fence.MatchString(line):
+			fenced = fence.FindStringSubmatch(line)[1]
+			cut(pos, false)
+			para = -1
+		case atxHeading.MatchString(line):
+			m := atxHeading.FindStringSubmatch(line)
+			enter(pos, len(m[1]), m[2])
+			para = -1
+		case para >= 0 && setextHeading.MatchString(line):
+			level := 1
+			if strings.TrimSpace(line)[0] == '-' {
+				level = 2
+			}
+			enter(para, level, strings.Join(strings.Fields(text[para:pos]), " "))
+			para = -1
+		case strings.TrimSpace(line) == "":
+			para = -1
+		default:
+			if para < 0 {
+				para = pos
+			}
+		}
+		pos += end
+	}
+	cut(len(text), fenced != "")
+	return sections
+}
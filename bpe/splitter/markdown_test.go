@@ -0,0 +1,89 @@
+package splitter
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const markdownDoc = "Intro text.\n" +
+	"\n" +
+	"# Title #\n" +
+	"\n" +
+	"Paragraph under the title.\n" +
+	"\n" +
+	"## Setup\n" +
+	"\n" +
+	"Install it:\n" +
+	"\n" +
+	"```go\n" +
+	"# not a heading\n" +
+	"func main() {\n" +
+	"\tprintln(\"hello, world\")\n" +
+	"}\n" +
+	"```\n" +
+	"\n" +
+	"More setup text.\n" +
+	"\n" +
+	"Other section\n" +
+	"-------------\n" +
+	"\n" +
+	"Text.\n" +
+	"\n" +
+	"# Second title\n" +
+	"Last words.\n"
+
+func TestMarkdownSections(t *testing.T) {
+	type section struct {
+		text    string
+		context []string
+		code    bool
+	}
+	var got []section
+	for _, sec := range MarkdownSections(markdownDoc) {
+		got = append(got, section{markdownDoc[sec.Start:sec.End], sec.Context, sec.Code})
+	}
+	want := []section{
+		{"Intro text.\n\n", nil, false},
+		{"# Title #\n\nParagraph under the title.\n\n", []string{"Title"}, false},
+		{"## Setup\n\nInstall it:\n\n", []string{"Title", "Setup"}, false},
+		{"```go\n# not a heading\nfunc main() {\n\tprintln(\"hello, world\")\n}\n```\n", []string{"Title", "Setup"}, true},
+		{"\nMore setup text.\n\n", []string{"Title", "Setup"}, false},
+		{"Other section\n-------------\n\nText.\n\n", []string{"Title", "Other section"}, false},
+		{"# Second title\nLast words.\n", []string{"Second title"}, false},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("MarkdownSections:\n%+v\nwant:\n%+v", got, want)
+	}
+}
+
+func TestMarkdownSectionsUnclosedFence(t *testing.T) {
+	text := "# Code\n~~~~\n# still code\n~~~\n"
+	got := MarkdownSections(text)
+	if len(got) != 2 || !got[1].Code || got[1].End != len(text) {
+		t.Errorf("MarkdownSections(%q) = %+v, want code block to the end", text, got)
+	}
+}
+
+func TestSplitMarkdown(t *testing.T) {
+	s, enc := newSplitter(t, "o200k_base", 1000, 0)
+	chunks := s.SplitMarkdown(markdownDoc)
+	var contexts []string
+	for _, c := range chunks {
+		contexts = append(contexts, strings.Join(c.Context, " > "))
+	}
+	want := []string{"", "Title", "Title > Setup", "Title > Other section", "Second title"}
+	if !reflect.DeepEqual(contexts, want) {
+		t.Errorf("SplitMarkdown contexts = %q, want %q", contexts, want)
+	}
+	checkChunks(t, enc, markdownDoc, 1000, 0, chunks)
+
+	// A code block that does not fit is split between lines.
+	s, _ = newSplitter(t, "o200k_base", 8, 0)
+	for _, c := range s.SplitMarkdown(markdownDoc) {
+		if c.Start >= strings.Index(markdownDoc, "```go") && c.End < strings.Index(markdownDoc, "\nMore") &&
+			!strings.HasSuffix(c.Text, "\n") {
+			t.Errorf("code chunk %q does not end at a line end", c.Text)
+		}
+	}
+}
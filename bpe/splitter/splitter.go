@@ -0,0 +1,279 @@
+package splitter
+
+import (
+	"cmp"
+	"errors"
+	"math"
+	"regexp"
+	"slices"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// A Chunk is a piece of a document.
+type Chunk struct {
+	Text   string `json:"text"`
+	Start  int    `json:"start"`  // byte offset of Text in the document
+	End    int    `json:"end"`    // byte offset of the end of Text
+	Tokens int    `json:"tokens"` // exact token count of Text
+
+	// Context describes where in the document the chunk came from, such
+	// as the path of Markdown headings above it. It is not part of Text.
+	Context []string `json:"context,omitempty"`
+}
+
+// A Section is a part of a document that is split on its own, so that no
+// chunk spans two sections unless they share a context. Sections let
+// structure-aware splitters, such as SplitMarkdown and SplitGo, use the
+// same token budget logic as Split.
+type Section struct {
+	Start, End int      // byte offsets in the document
+	Context    []string // context of the chunks of the section
+
+	// Code marks sections of source code, which are split at blank lines
+	// and then at line ends rather than at paragraphs and sentences.
+	Code bool
+}
+
+// A Splitter splits text into chunks that fit a token budget.
+type Splitter struct {
+	enc     bpe.Encoder
+	max     int
+	overlap int
+}
+
+// New returns a Splitter that uses enc to split text into chunks of at
+// most maxTokens tokens, each starting with up to overlap tokens from the
+// end of the previous chunk.
+func New(enc bpe.Encoder, maxTokens, overlap int) (*Splitter, error) {
+	if maxTokens <= 0 {
+		return nil, errors.New("max tokens must be positive")
+	}
+	if overlap < 0 || overlap >= maxTokens {
+		return nil, errors.New("overlap must be at least 0 and less than max tokens")
+	}
+	return &Splitter{enc: enc, max: maxTokens, overlap: overlap}, nil
+}
+
+// Split splits text into chunks. Chunks end at the coarsest boundary that
+// lets them fit: a paragraph break, then the end of a sentence, then a
+// space between words, and only then between tokens. Chunks never split a
+// UTF-8 sequence, so a chunk may only exceed the budget when a single
+// character needs more tokens than that.
+//
+// Each chunk after the first starts with up to the overlap of tokens
+// repeated from the end of the previous one, beginning at a word boundary
+// where possible.
+func (s *Splitter) Split(text string) []Chunk {
+	return s.SplitSections(text, []Section{{Start: 0, End: len(text)}})
+}
+
+// SplitSections is like Split, but splits each of the given sections of
+// text separately and gives their chunks the section's Context. Adjacent
+// sections with the same context are split together, as if they were one.
+// Sections must be in order and must not overlap; text outside of them
+// is left out.
+func (s *Splitter) SplitSections(text string, sections []Section) []Chunk {
+	var chunks []Chunk
+	for i := 0; i < len(sections); {
+		var pieces []piece
+		j := i
+		for ; j < len(sections) && slices.Equal(sections[j].Context, sections[i].Context); j++ {
+			sec := sections[j]
+			bounds := boundaries
+			if sec.Code {
+				bounds = codeBoundaries
+			}
+			pieces = s.pieces(pieces, text[sec.Start:sec.End], sec.Start, bounds)
+		}
+		chunks = s.chunks(chunks, text, pieces, sections[i].Context)
+		i = j
+	}
+	return chunks
+}
+
+// chunks appends to dst the chunks made of pieces, in the given context.
+func (s *Splitter) chunks(dst []Chunk, text string, pieces []piece, context []string) []Chunk {
+	first := len(dst)
+	for i := 0; i < len(pieces); {
+		start := pieces[i].start
+		if len(dst) > first && s.overlap > 0 {
+			start = s.overlapStart(text, dst[len(dst)-1])
+		}
+		j, n := s.fill(text, pieces, start, i)
+		if j == i && start != pieces[i].start {
+			// The overlap leaves no room for the next piece.
+			start = pieces[i].start
+			j, n = s.fill(text, pieces, start, i)
+		}
+		if j == i {
+			// A single piece that cannot be made to fit.
+			j, n = i+1, s.enc.Count(text[start:pieces[i].end])
+		}
+		end := pieces[j-1].end
+		dst = append(dst, Chunk{Text: text[start:end], Start: start, End: end, Tokens: n, Context: context})
+		i = j
+	}
+	return dst
+}
+
+// A piece is a span of text that fits the budget on its own.
+type piece struct {
+	start, end int
+	tokens     int
+	level      int // boundary level at end: 0 for a paragraph break, and so on
+}
+
+// fill returns the index just past the last of pieces[i:] to put in a
+// chunk starting at start, and the token count of that chunk.
+//
+// Of the pieces that fit, the chunk ends after the one with the coarsest
+// boundary, as long as that keeps at least half of their tokens, so that
+// chunks end at paragraph breaks over sentence ends, and so on.
+func (s *Splitter) fill(text string, pieces []piece, start, i int) (j, n int) {
+	// Piece counts add up to roughly the count of their concatenation,
+	// so only the final candidate needs to be counted exactly.
+	sum := s.enc.Count(text[start:pieces[i].start])
+	j = i
+	for j < len(pieces) && sum+pieces[j].tokens <= s.max {
+		sum += pieces[j].tokens
+		j++
+	}
+	if j == i {
+		return i, 0
+	}
+
+	// Order the candidate ends by preference: coarser boundaries first,
+	// but ends that would leave out more than half of the tokens last.
+	candidates := make([]int, 0, j-i)
+	late := make(map[int]bool, j-i)
+	for k, cum := j, sum; k > i; k-- {
+		candidates = append(candidates, k)
+		late[k] = 2*cum >= sum
+		cum -= pieces[k-1].tokens
+	}
+	if j < len(pieces) {
+		slices.SortStableFunc(candidates, func(a, b int) int {
+			return cmp.Compare(rank(pieces, a, late[a]), rank(pieces, b, late[b]))
+		})
+	}
+	for _, k := range candidates {
+		if n = s.enc.Count(text[start:pieces[k-1].end]); n <= s.max {
+			return k, n
+		}
+	}
+	return i, 0
+}
+
+// rank orders the chunk ends after pieces[k-1] by their boundary level.
+func rank(pieces []piece, k int, late bool) int {
+	if !late {
+		return math.MaxInt
+	}
+	return pieces[k-1].level
+}
+
+// overlapStart returns where the chunk after prev starts so that it
+// repeats at most the overlap of tokens from the end of prev.
+func (s *Splitter) overlapStart(text string, prev Chunk) int {
+	tail, _ := bpe.TruncateTail(s.enc, prev.Text, s.overlap)
+	start := prev.End - len(tail)
+	if start == prev.Start || start == prev.End {
+		return prev.End
+	}
+	// Move forward out of a partial word, if the overlap has a word break.
+	r, _ := utf8.DecodeLastRuneInString(text[:start])
+	if !unicode.IsSpace(r) {
+		for i, r := range text[start:prev.End] {
+			if unicode.IsSpace(r) {
+				return start + i
+			}
+		}
+	}
+	return start
+}
+
+// A boundary is a kind of place where text can be split.
+type boundary struct {
+	re     *regexp.Regexp
+	before bool // split before a match rather than after it
+}
+
+// words splits text between words. Spaces between words go with the
+// following word, as they do in tokens.
+var words = boundary{re: regexp.MustCompile(`\s+`), before: true}
+
+// boundaries lists the boundaries between paragraphs, sentences, and
+// words, from the most to the least preferred place to split.
+var boundaries = []boundary{
+	{re: regexp.MustCompile(`\n[ \t\r]*\n\s*`)},
+	{re: regexp.MustCompile(`[.!?]+["'”’)\]]*\s+|[。！？]+`)},
+	words,
+}
+
+// codeBoundaries lists the boundaries used for source code: blank lines,
+// line ends, and spaces.
+var codeBoundaries = []boundary{
+	{re: regexp.MustCompile(`\n[ \t\r]*\n`)},
+	{re: regexp.MustCompile(`\n`)},
+	words,
+}
+
+// pieces appends to dst the pieces of text, which starts at offset base
+// in the document, splitting it at the given boundaries, coarsest first,
+// as needed for each piece to fit the budget.
+func (s *Splitter) pieces(dst []piece, text string, base int, bounds []boundary) []piece {
+	return s.split(dst, text, base, bounds, 0, 0)
+}
+
+// split is like pieces, but splits text at bounds[level] and finer
+// boundaries, and the end of text is a boundary of level end.
+func (s *Splitter) split(dst []piece, text string, base int, bounds []boundary, level, end int) []piece {
+	if text == "" {
+		return dst
+	}
+	if n := s.enc.Count(text); n <= s.max {
+		return append(dst, piece{base, base + len(text), n, end})
+	}
+	if level == len(bounds) {
+		return s.tokenPieces(dst, text, base, level, end)
+	}
+	b := bounds[level]
+	start := 0
+	for _, loc := range b.re.FindAllStringIndex(text, -1) {
+		cut := loc[1]
+		if b.before {
+			cut = loc[0]
+		}
+		if cut == 0 || cut == len(text) {
+			continue
+		}
+		dst = s.split(dst, text[start:cut], base+start, bounds, level+1, level)
+		start = cut
+	}
+	return s.split(dst, text[start:], base+start, bounds, level+1, end)
+}
+
+// tokenPieces appends to dst pieces of text split between tokens, which
+// are boundaries of the given level. The end of text is a boundary of
+// level end.
+func (s *Splitter) tokenPieces(dst []piece, text string, base, level, end int) []piece {
+	for text != "" {
+		head, n := bpe.TruncateHead(s.enc, text, s.max)
+		if head == "" {
+			// A single character that needs more tokens than the budget.
+			_, size := utf8.DecodeRuneInString(text)
+			head, n = text[:size], s.enc.Count(text[:size])
+		}
+		l := level
+		if len(head) == len(text) {
+			l = end
+		}
+		dst = append(dst, piece{base, base + len(head), n, l})
+		text = text[len(head):]
+		base += len(head)
+	}
+	return dst
+}
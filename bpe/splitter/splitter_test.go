@@ -0,0 +1,152 @@
+package splitter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+const document = `Tokenizers split text into tokens. Each model has its own vocabulary, so the same text can have different counts.
+
+Chunking documents for retrieval needs care! Chunks that are too large waste context; chunks that are too small lose meaning.
+
+日本語の文も分割できます。句読点で区切られます。
+
+A very long paragraph follows, with no sentence breaks at all and many words in a row that keep going and going until it is far longer than any reasonable chunk size would allow for a single piece of text`
+
+func newSplitter(t *testing.T, encoding string, max, overlap int) (*Splitter, bpe.Encoder) {
+	t.Helper()
+	enc, err := bpe.NewEncoder(encoding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := New(enc, max, overlap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, enc
+}
+
+func TestSplitInvariants(t *testing.T) {
+	texts := []string{"", "short", document, strings.Repeat("🎉", 40), strings.Repeat("word ", 200)}
+	for _, encoding := range []string{"o200k_base", "anthropic"} {
+		for _, max := range []int{1, 2, 5, 16, 40, 1000} {
+			for _, overlap := range []int{0, max / 4, max / 2} {
+				s, enc := newSplitter(t, encoding, max, overlap)
+				for _, text := range texts {
+					checkChunks(t, enc, text, max, overlap, s.Split(text))
+				}
+			}
+		}
+	}
+}
+
+func checkChunks(t *testing.T, enc bpe.Encoder, text string, max, overlap int, chunks []Chunk) {
+	t.Helper()
+	if text == "" {
+		if len(chunks) != 0 {
+			t.Errorf("Split(%q) = %v, want no chunks", text, chunks)
+		}
+		return
+	}
+	end := 0
+	for i, c := range chunks {
+		if c.Text != text[c.Start:c.End] {
+			t.Fatalf("max %d overlap %d: chunk %d text %q does not match offsets [%d, %d)", max, overlap, i, c.Text, c.Start, c.End)
+		}
+		if n := enc.Count(c.Text); c.Tokens != n {
+			t.Errorf("max %d overlap %d: chunk %d %q has Tokens %d, counted %d", max, overlap, i, c.Text, c.Tokens, n)
+		}
+		if c.Tokens > max && len([]rune(c.Text)) > 1 {
+			t.Errorf("max %d overlap %d: chunk %d %q has %d tokens", max, overlap, i, c.Text, c.Tokens)
+		}
+		if c.Start > end || c.End <= end {
+			t.Fatalf("max %d overlap %d: chunk %d [%d, %d) does not continue from %d", max, overlap, i, c.Start, c.End, end)
+		}
+		if overlap == 0 && c.Start != end {
+			t.Errorf("max %d: chunk %d starts at %d, want %d without overlap", max, i, c.Start, end)
+		}
+		if i > 0 && overlap > 0 {
+			if n := enc.Count(text[c.Start:end]); n > overlap+1 {
+				t.Errorf("max %d overlap %d: chunk %d repeats %q, %d tokens", max, overlap, i, text[c.Start:end], n)
+			}
+		}
+		end = c.End
+	}
+	if end != len(text) {
+		t.Errorf("max %d overlap %d: chunks end at %d, want %d", max, overlap, end, len(text))
+	}
+}
+
+func TestSplitBoundaries(t *testing.T) {
+	s, _ := newSplitter(t, "o200k_base", 30, 0)
+	var got []string
+	for _, c := range s.Split(document) {
+		got = append(got, c.Text)
+	}
+	want := []string{
+		"Tokenizers split text into tokens. Each model has its own vocabulary, so the same text can have different counts.\n\n",
+		"Chunking documents for retrieval needs care! Chunks that are too large waste context; chunks that are too small lose meaning.\n\n",
+		"日本語の文も分割できます。句読点で区切られます。\n\n",
+		"A very long paragraph follows, with no sentence breaks at all and many words in a row that keep going and going until it is far longer than any",
+		" reasonable chunk size would allow for a single piece of text",
+	}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Split:\n%q\nwant:\n%q", got, want)
+	}
+}
+
+func TestSplitOverlap(t *testing.T) {
+	s, _ := newSplitter(t, "o200k_base", 10, 3)
+	chunks := s.Split("one two three four five six seven eight nine ten eleven twelve thirteen fourteen fifteen")
+	for i := 1; i < len(chunks); i++ {
+		prev, c := chunks[i-1], chunks[i]
+		if c.Start >= prev.End {
+			t.Errorf("chunk %d %q does not overlap %q", i, c.Text, prev.Text)
+		}
+		if c.Text[0] != ' ' {
+			t.Errorf("chunk %d %q does not start at a word boundary", i, c.Text)
+		}
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tt := range []struct{ max, overlap int }{{0, 0}, {-1, 0}, {10, 10}, {10, -1}} {
+		if _, err := New(enc, tt.max, tt.overlap); err == nil {
+			t.Errorf("New(%d, %d) succeeded, want error", tt.max, tt.overlap)
+		}
+	}
+}
+
+func TestSplitSentences(t *testing.T) {
+	s, _ := newSplitter(t, "o200k_base", 12, 0)
+	var got []string
+	for _, c := range s.Split("First sentence here. Second one is a bit longer than that! Third.") {
+		got = append(got, c.Text)
+	}
+	want := []string{"First sentence here. ", "Second one is a bit longer than that! Third."}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Split = %q, want %q", got, want)
+	}
+}
+
+func BenchmarkSplit(b *testing.B) {
+	enc, err := bpe.NewEncoder("o200k_base")
+	if err != nil {
+		b.Fatal(err)
+	}
+	s, err := New(enc, 512, 64)
+	if err != nil {
+		b.Fatal(err)
+	}
+	text := strings.Repeat(document+"\n\n", 100)
+	b.SetBytes(int64(len(text)))
+	for b.Loop() {
+		s.Split(text)
+	}
+}
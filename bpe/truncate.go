@@ -0,0 +1,127 @@
+package bpe
+
+import "unicode/utf8"
+
+// TruncateHead returns the longest prefix of text that fits within max
+// tokens, along with its token count. The prefix ends at a token boundary
+// and is never cut inside a UTF-8 sequence; if text fits, it is returned
+// whole.
+//
+// Tokenizing a prefix on its own can give different tokens than it had
+// as part of text, so the count is computed for the prefix itself and is
+// exact.
+func TruncateHead(enc Encoder, text string, max int) (string, int) {
+	if max <= 0 {
+		return "", 0
+	}
+	_, offsets := enc.EncodeWithOffsets(text)
+	if len(offsets) <= max {
+		return text, len(offsets)
+	}
+
+	// Candidate cuts are the ends of tokens, moved back to a rune start.
+	cut := func(i int) int {
+		if i < 0 {
+			return 0
+		}
+		end := offsets[i][1]
+		for end > 0 && end < len(text) && !utf8.RuneStart(text[end]) {
+			end--
+		}
+		return end
+	}
+
+	// Start at the end of token max, back off until the prefix fits,
+	// then take any following cuts that still fit.
+	i := max - 1
+	n := enc.Count(text[:cut(i)])
+	for ; n > max; n = enc.Count(text[:cut(i)]) {
+		i--
+	}
+	for i+1 < len(offsets) {
+		m := enc.Count(text[:cut(i+1)])
+		if m > max {
+			break
+		}
+		i, n = i+1, m
+	}
+	return text[:cut(i)], n
+}
+
+// TruncateTail returns the longest suffix of text that fits within max
+// tokens, along with its token count. The suffix starts at a token
+// boundary and is never cut inside a UTF-8 sequence; if text fits, it is
+// returned whole.
+//
+// As with TruncateHead, the count is computed for the suffix itself and
+// is exact.
+func TruncateTail(enc Encoder, text string, max int) (string, int) {
+	if max <= 0 {
+		return "", 0
+	}
+	_, offsets := enc.EncodeWithOffsets(text)
+	if len(offsets) <= max {
+		return text, len(offsets)
+	}
+
+	// Candidate cuts are the starts of tokens, moved forward to a rune start.
+	cut := func(i int) int {
+		if i >= len(offsets) {
+			return len(text)
+		}
+		start := offsets[i][0]
+		for start < len(text) && !utf8.RuneStart(text[start]) {
+			start++
+		}
+		return start
+	}
+
+	i := len(offsets) - max
+	n := enc.Count(text[cut(i):])
+	for ; n > max; n = enc.Count(text[cut(i):]) {
+		i++
+	}
+	for i > 0 {
+		m := enc.Count(text[cut(i-1):])
+		if m > max {
+			break
+		}
+		i, n = i-1, m
+	}
+	return text[cut(i):], n
+}
+
+// TruncateMiddle shortens text to fit within max tokens by keeping its
+// beginning and end and replacing the middle with marker, such as "…".
+// It returns the result and its exact token count. If text fits, it is
+// returned unchanged; if not even marker fits, TruncateMiddle returns "".
+//
+// The tokens that remain after the marker are split evenly between the
+// beginning and the end, with any odd token going to the beginning.
+func TruncateMiddle(enc Encoder, text string, max int, marker string) (string, int) {
+	if n := enc.Count(text); n <= max {
+		return text, n
+	}
+	budget := max - enc.Count(marker)
+	if budget < 0 {
+		return "", 0
+	}
+
+	// Tokens can merge across the joins, so shrink the halves until the
+	// whole result fits.
+	tail := budget / 2
+	head := budget - tail
+	for {
+		h, _ := TruncateHead(enc, text, head)
+		t, _ := TruncateTail(enc, text[len(h):], tail)
+		s := h + marker + t
+		if n := enc.Count(s); n <= max {
+			return s, n
+		}
+		if tail >= head {
+			tail--
+		} else {
+			head--
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bpe
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+var truncateTexts = []string{
+	"",
+	"Hello, world!",
+	"The quick brown fox jumps over the lazy dog.\n\nIt was not amused.",
+	"日本語のテキストを切り詰める。",
+	"emoji 🎉🎉🎉 and flags 🇯🇵🇺🇸 everywhere",
+	"café ½ ﬁne ™",
+	strings.Repeat("a", 300),
+}
+
+func TestTruncate(t *testing.T) {
+	for _, name := range []string{"anthropic", "o200k_base", "cl100k_base"} {
+		enc, err := NewEncoder(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range truncateTexts {
+			total := enc.Count(text)
+			for max := range total + 2 {
+				head, n := TruncateHead(enc, text, max)
+				checkTruncated(t, enc, "TruncateHead", text, max, head, n)
+				if !strings.HasPrefix(text, head) {
+					t.Errorf("%s: TruncateHead(%q, %d) = %q, not a prefix", name, text, max, head)
+				}
+
+				tail, n := TruncateTail(enc, text, max)
+				checkTruncated(t, enc, "TruncateTail", text, max, tail, n)
+				if !strings.HasSuffix(text, tail) {
+					t.Errorf("%s: TruncateTail(%q, %d) = %q, not a suffix", name, text, max, tail)
+				}
+
+				if max >= total && (head != text || tail != text) {
+					t.Errorf("%s: truncating %q to %d = %q, %q, want it whole", name, text, max, head, tail)
+				}
+
+				mid, n := TruncateMiddle(enc, text, max, "…")
+				checkTruncated(t, enc, "TruncateMiddle", text, max, mid, n)
+			}
+		}
+	}
+}
+
+func checkTruncated(t *testing.T, enc Encoder, fn, text string, max int, got string, n int) {
+	t.Helper()
+	if !utf8.ValidString(got) {
+		t.Errorf("%s(%q, %d) = %q, invalid UTF-8", fn, text, max, got)
+	}
+	if want := enc.Count(got); n != want || n > max {
+		t.Errorf("%s(%q, %d) = %q, %d tokens; counted %d, max %d", fn, text, max, got, n, want, max)
+	}
+}
+
+func TestTruncateExamples(t *testing.T) {
+	enc, err := NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := "The quick brown fox jumps over the lazy dog"
+	tests := []struct {
+		fn    string
+		max   int
+		want  string
+		wantN int
+	}{
+		{"head", 3, "The quick brown", 3},
+		{"tail", 3, " the lazy dog", 3},
+		{"middle", 5, "The quick… lazy dog", 5},
+		{"middle", 0, "", 0},
+	}
+	for _, tt := range tests {
+		var got string
+		var n int
+		switch tt.fn {
+		case "head":
+			got, n = TruncateHead(enc, text, tt.max)
+		case "tail":
+			got, n = TruncateTail(enc, text, tt.max)
+		case "middle":
+			got, n = TruncateMiddle(enc, text, tt.max, "…")
+		}
+		if got != tt.want || n != tt.wantN {
+			t.Errorf("truncate %s to %d = %q, %d, want %q, %d", tt.fn, tt.max, got, n, tt.want, tt.wantN)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/tokencount/internal/ignore"
+)
+
+// A budget limits the tokens in each input and in all of them together.
+// A limit of 0 means no limit.
+type budget struct {
+	max      int // tokens in each input
+	maxTotal int // tokens in all inputs
+
+	// rules are the limits read from a budget file, whose globs are
+	// matched against paths relative to dir. The last matching rule wins
+	// over the others and over max.
+	rules []budgetRule
+	dir   string
+}
+
+// A budgetRule limits the tokens in the files that match a glob.
+type budgetRule struct {
+	pattern ignore.Pattern
+	limit   int
+}
+
+// readBudgetFile reads the named budget file into b. Each line of the
+// file holds a glob, in the syntax of -include, and a positive limit,
+// separated by spaces. Blank lines and lines starting with # are skipped.
+func (b *budget) readBudgetFile(name string) error {
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return fmt.Errorf("failed to read budget file: %w", err)
+	}
+	if b.dir, err = filepath.Abs(filepath.Dir(name)); err != nil {
+		return err
+	}
+
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for line := 1; s.Scan(); line++ {
+		text := strings.TrimSpace(s.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return fmt.Errorf("%s:%d: want glob and limit, got %d fields", name, line, len(fields))
+		}
+		p, ok := ignore.ParsePattern("", fields[0])
+		if !ok {
+			return fmt.Errorf("%s:%d: invalid glob %q", name, line, fields[0])
+		}
+		limit, err := strconv.Atoi(fields[1])
+		if err != nil || limit <= 0 {
+			return fmt.Errorf("%s:%d: invalid limit %q", name, line, fields[1])
+		}
+		b.rules = append(b.rules, budgetRule{p, limit})
+	}
+	return s.Err()
+}
+
+// limit returns the limit on the tokens in the named input.
+func (b *budget) limit(name string) int {
+	if len(b.rules) == 0 || name == "-" {
+		return b.max
+	}
+	abs, err := filepath.Abs(name)
+	if err != nil {
+		return b.max
+	}
+	rel, err := filepath.Rel(b.dir, abs)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return b.max
+	}
+	rel = filepath.ToSlash(rel)
+	for i := len(b.rules) - 1; i >= 0; i-- {
+		if (ignore.Matcher{b.rules[i].pattern}).Match(rel, false) {
+			return b.rules[i].limit
+		}
+	}
+	return b.max
+}
+
+// check prints to w each input, in each encoding, that is over its
+// limit, and the total if it is over, and reports whether any were.
+func (b *budget) check(w io.Writer, counts []count, encodings []string) bool {
+	over := false
+	report := func(name string, i, tokens, limit int) {
+		if limit <= 0 || tokens <= limit {
+			return
+		}
+		over = true
+		unit := "tokens"
+		if len(encodings) > 1 {
+			unit = encodings[i] + " tokens"
+		}
+		fmt.Fprintf(w, "tokencount: %s: %d %s, %d over the budget of %d\n", name, tokens, unit, tokens-limit, limit)
+	}
+
+	total := make([]int, len(encodings))
+	for _, c := range counts {
+		if c.err != nil {
+			continue
+		}
+		limit := b.limit(c.name)
+		for i, n := range c.tokens {
+			report(c.name, i, n, limit)
+			total[i] += n
+		}
+	}
+	for i, n := range total {
+		report("total", i, n, b.maxTotal)
+	}
+	return over
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// idFormats are the formats of token IDs for encode and decode:
+// decimal IDs separated by spaces, a JSON array, or little-endian uint32s.
+var idFormats = []string{"text", "json", "binary"}
+
+// runEncode implements the encode subcommand, which prints the token IDs
+// of its input.
+func runEncode(args []string) error {
+	fs := flag.NewFlagSet("encode", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tokencount encode [-format text|json|binary] [file ...]\n")
+		fs.PrintDefaults()
+	}
+	encFlags := addEncodingFlags(fs)
+	format := fs.String("format", "text", "Output `format`: IDs separated by spaces (text), a JSON array (json), or little-endian uint32s (binary)")
+	fs.Parse(args)
+
+	if !slices.Contains(idFormats, *format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	enc, err := encFlags.encoder()
+	if err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"} // Use stdin if no files specified
+	}
+
+	// Each input is written on its own line, except in binary, where the
+	// IDs of all inputs follow each other.
+	w := bufio.NewWriter(os.Stdout)
+	for _, file := range files {
+		content, err := readInput(file)
+		if err != nil {
+			return err
+		}
+		tokens := enc.Encode(string(content))
+		switch *format {
+		case "text":
+			w.WriteString(strings.Join(intStrings(tokens), " ") + "\n")
+		case "json":
+			if tokens == nil {
+				tokens = []int{}
+			}
+			if err := json.NewEncoder(w).Encode(tokens); err != nil {
+				return err
+			}
+		case "binary":
+			for _, id := range tokens {
+				binary.Write(w, binary.LittleEndian, uint32(id))
+			}
+		}
+	}
+	return w.Flush()
+}
+
+// runDecode implements the decode subcommand, which prints the text of
+// the token IDs in its input.
+func runDecode(args []string) error {
+	fs := flag.NewFlagSet("decode", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tokencount decode [-format text|json|binary] [file ...]\n")
+		fs.PrintDefaults()
+	}
+	encFlags := addEncodingFlags(fs)
+	format := fs.String("format", "text", "Input `format`: IDs separated by spaces or commas (text), JSON arrays (json), or little-endian uint32s (binary)")
+	fs.Parse(args)
+
+	if !slices.Contains(idFormats, *format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	enc, err := encFlags.encoder()
+	if err != nil {
+		return err
+	}
+	dec, ok := enc.(bpe.Decoder)
+	if !ok {
+		return fmt.Errorf("encoding does not support decoding")
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"} // Use stdin if no files specified
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for _, file := range files {
+		content, err := readInput(file)
+		if err != nil {
+			return err
+		}
+		tokens, err := parseIDs(*format, content)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		text, err := dec.Decode(tokens)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		w.WriteString(text)
+	}
+	return w.Flush()
+}
+
+// parseIDs parses the token IDs in data, which is in the named format.
+func parseIDs(format string, data []byte) ([]int, error) {
+	var ids []int
+	switch format {
+	case "text":
+		fields := strings.FieldsFunc(string(data), func(r rune) bool {
+			return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+		})
+		for _, f := range fields {
+			id, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("invalid token ID %q", f)
+			}
+			ids = append(ids, id)
+		}
+
+	case "json":
+		// The input may hold several arrays, as encode writes for several files.
+		d := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var a []int
+			if err := d.Decode(&a); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, fmt.Errorf("invalid JSON: %w", err)
+			}
+			ids = append(ids, a...)
+		}
+
+	case "binary":
+		if len(data)%4 != 0 {
+			return nil, errors.New("binary input is not a whole number of uint32s")
+		}
+		for i := 0; i < len(data); i += 4 {
+			ids = append(ids, int(binary.LittleEndian.Uint32(data[i:])))
+		}
+	}
+	return ids, nil
+}
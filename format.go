@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// formats are the values of the -format flag.
+var formats = []string{"text", "json", "jsonl", "csv", "tsv"}
+
+// schemaVersion is the version of the structured output formats. Fields
+// may be added without changing it, but it changes if a field is removed,
+// renamed, or changes meaning.
+const schemaVersion = 1
+
+// A record describes one input in the structured output formats.
+type record struct {
+	Path     string `json:"path"`
+	Encoding string `json:"encoding"`
+	Tokens   int    `json:"tokens"`
+	Bytes    int    `json:"bytes"`
+	Chars    int    `json:"chars"`
+	Lines    int    `json:"lines"`
+	Words    int    `json:"words"`
+	Error    string `json:"error,omitempty"` // set if the input could not be counted
+}
+
+// A summary holds the totals of all inputs in the structured output formats.
+// Inputs that could not be counted add to Errors but not to the totals.
+type summary struct {
+	Files  int            `json:"files"`
+	Errors int            `json:"errors"`
+	Tokens map[string]int `json:"tokens"` // by encoding
+	Bytes  int            `json:"bytes"`
+	Chars  int            `json:"chars"`
+	Lines  int            `json:"lines"`
+	Words  int            `json:"words"`
+}
+
+// header is the header row of the csv and tsv formats. The type column
+// tells the rows of inputs, of type "file", from the totals, of type
+// "summary", whose path is empty.
+var header = []string{"type", "path", "encoding", "tokens", "bytes", "chars", "lines", "words", "error"}
+
+// writeCounts writes counts to w in the named format.
+func writeCounts(w io.Writer, format string, counts []count, cols columns) error {
+	if format == "text" {
+		return printCounts(w, counts, cols)
+	}
+
+	// There is a record for each input in each encoding.
+	records := []record{}
+	s := summary{Files: len(counts), Tokens: make(map[string]int)}
+	for _, c := range counts {
+		for i, encoding := range cols.encodings {
+			r := record{
+				Path:     c.name,
+				Encoding: encoding,
+				Bytes:    c.bytes,
+				Chars:    c.chars,
+				Lines:    c.lines,
+				Words:    c.words,
+			}
+			if c.err != nil {
+				r.Error = c.err.Error()
+			} else {
+				r.Tokens = c.tokens[i]
+				s.Tokens[encoding] += c.tokens[i]
+			}
+			records = append(records, r)
+		}
+		if c.err != nil {
+			s.Errors++
+			continue
+		}
+		s.Bytes += c.bytes
+		s.Chars += c.chars
+		s.Lines += c.lines
+		s.Words += c.words
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			Version int      `json:"version"`
+			Files   []record `json:"files"`
+			Summary summary  `json:"summary"`
+		}{schemaVersion, records, s})
+
+	case "jsonl":
+		// Each line has a type: one "file" line per input, then a "summary".
+		enc := json.NewEncoder(w)
+		for _, r := range records {
+			line := struct {
+				Type string `json:"type"`
+				record
+			}{"file", r}
+			if err := enc.Encode(line); err != nil {
+				return err
+			}
+		}
+		return enc.Encode(struct {
+			Type    string `json:"type"`
+			Version int    `json:"version"`
+			summary
+		}{"summary", schemaVersion, s})
+
+	case "csv", "tsv":
+		// The totals follow the inputs, one summary row per encoding.
+		cw := csv.NewWriter(w)
+		if format == "tsv" {
+			cw.Comma = '\t'
+		}
+		cw.Write(header)
+		for _, r := range records {
+			cw.Write(r.row("file"))
+		}
+		for _, encoding := range cols.encodings {
+			total := record{Encoding: encoding, Tokens: s.Tokens[encoding], Bytes: s.Bytes, Chars: s.Chars, Lines: s.Lines, Words: s.Words}
+			cw.Write(total.row("summary"))
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return fmt.Errorf("unknown format %q", format)
+}
+
+// row returns the fields of r, a row of the given type, in the order of
+// header.
+func (r record) row(typ string) []string {
+	return []string{
+		typ,
+		r.Path,
+		r.Encoding,
+		strconv.Itoa(r.Tokens),
+		strconv.Itoa(r.Bytes),
+		strconv.Itoa(r.Chars),
+		strconv.Itoa(r.Lines),
+		strconv.Itoa(r.Words),
+		r.Error,
+	}
+}
@@ -0,0 +1,197 @@
+// Package bytepair implements the byte pair merge shared by the tokenizers.
+//
+// A piece of text is split into single bytes, and adjacent parts are merged
+// repeatedly, always taking the pair with the lowest rank (and the leftmost
+// one among equal ranks), until no adjacent pair is in the vocabulary.
+// Parts are kept in a linked list and candidate pairs in a min-heap, so a
+// piece of n bytes is encoded in O(n log n) time.
+package bytepair
+
+import "sync"
+
+// none marks a part that cannot be merged with its successor.
+const none = -1
+
+// A pair is a candidate merge of the part starting at pos with its successor.
+type pair struct {
+	rank int
+	pos  int
+}
+
+// state is the scratch space for merging one piece.
+type state struct {
+	next []int  // start of the following part, for each part start
+	prev []int  // start of the preceding part, or -1
+	rank []int  // rank of merging each part with its successor, or none
+	heap []pair // candidate merges, possibly stale
+}
+
+var statePool = sync.Pool{
+	New: func() any { return new(state) },
+}
+
+// Append applies byte pair merges to piece using ranks and appends the
+// ranks of the resulting tokens to dst. Parts of piece that are not in
+// ranks once no more merges apply are dropped.
+func Append(dst []int, ranks map[string]int, piece string) []int {
+	if r, ok := ranks[piece]; ok {
+		return append(dst, r)
+	}
+	if len(piece) <= 1 {
+		return dst
+	}
+
+	s := statePool.Get().(*state)
+	defer statePool.Put(s)
+	s.merge(ranks, piece)
+	for i := 0; i < len(piece); i = s.next[i] {
+		if r, ok := ranks[piece[i:s.next[i]]]; ok {
+			dst = append(dst, r)
+		}
+	}
+	return dst
+}
+
+// AppendOffsets is like Append but also appends to offsets the bounds of
+// each token within piece, shifted by base.
+func AppendOffsets(dst []int, offsets [][2]int, ranks map[string]int, piece string, base int) ([]int, [][2]int) {
+	if r, ok := ranks[piece]; ok {
+		return append(dst, r), append(offsets, [2]int{base, base + len(piece)})
+	}
+	if len(piece) <= 1 {
+		return dst, offsets
+	}
+
+	s := statePool.Get().(*state)
+	defer statePool.Put(s)
+	s.merge(ranks, piece)
+	for i := 0; i < len(piece); i = s.next[i] {
+		if r, ok := ranks[piece[i:s.next[i]]]; ok {
+			dst = append(dst, r)
+			offsets = append(offsets, [2]int{base + i, base + s.next[i]})
+		}
+	}
+	return dst, offsets
+}
+
+// merge merges the parts of piece until no adjacent pair is in ranks,
+// leaving the resulting parts linked through s.next.
+func (s *state) merge(ranks map[string]int, piece string) {
+	n := len(piece)
+	s.next = resize(s.next, n)
+	s.prev = resize(s.prev, n)
+	s.rank = resize(s.rank, n)
+	s.heap = s.heap[:0]
+
+	for i := range n {
+		s.next[i] = i + 1
+		s.prev[i] = i - 1
+	}
+	for i := range n {
+		s.rank[i] = s.pairRank(ranks, piece, i)
+		if s.rank[i] != none {
+			s.heap = append(s.heap, pair{s.rank[i], i})
+		}
+	}
+	for i := len(s.heap)/2 - 1; i >= 0; i-- {
+		s.down(i)
+	}
+
+	for len(s.heap) > 0 {
+		p := s.pop()
+		if s.rank[p.pos] != p.rank {
+			continue // stale: the part was merged or its successor changed
+		}
+
+		// Merge the part at p.pos with its successor.
+		i := p.pos
+		j := s.next[i]
+		s.next[i] = s.next[j]
+		if s.next[i] < n {
+			s.prev[s.next[i]] = i
+		}
+		s.rank[j] = none
+
+		s.update(ranks, piece, i)
+		if k := s.prev[i]; k >= 0 {
+			s.update(ranks, piece, k)
+		}
+	}
+}
+
+// pairRank returns the rank of merging the part at i with its successor.
+func (s *state) pairRank(ranks map[string]int, piece string, i int) int {
+	j := s.next[i]
+	if j >= len(piece) {
+		return none
+	}
+	if r, ok := ranks[piece[i:s.next[j]]]; ok {
+		return r
+	}
+	return none
+}
+
+// update recomputes the merge rank of the part at i after a neighbor changed.
+func (s *state) update(ranks map[string]int, piece string, i int) {
+	s.rank[i] = s.pairRank(ranks, piece, i)
+	if s.rank[i] != none {
+		s.push(pair{s.rank[i], i})
+	}
+}
+
+func (s *state) less(a, b pair) bool {
+	return a.rank < b.rank || a.rank == b.rank && a.pos < b.pos
+}
+
+func (s *state) push(p pair) {
+	s.heap = append(s.heap, p)
+	s.up(len(s.heap) - 1)
+}
+
+func (s *state) pop() pair {
+	h := s.heap
+	top := h[0]
+	last := len(h) - 1
+	h[0] = h[last]
+	s.heap = h[:last]
+	s.down(0)
+	return top
+}
+
+func (s *state) up(i int) {
+	h := s.heap
+	for i > 0 {
+		parent := (i - 1) / 2
+		if !s.less(h[i], h[parent]) {
+			break
+		}
+		h[i], h[parent] = h[parent], h[i]
+		i = parent
+	}
+}
+
+func (s *state) down(i int) {
+	h := s.heap
+	for {
+		least := i
+		if l := 2*i + 1; l < len(h) && s.less(h[l], h[least]) {
+			least = l
+		}
+		if r := 2*i + 2; r < len(h) && s.less(h[r], h[least]) {
+			least = r
+		}
+		if least == i {
+			return
+		}
+		h[i], h[least] = h[least], h[i]
+		i = least
+	}
+}
+
+// resize returns a slice of length n, reusing b's storage if possible.
+func resize(b []int, n int) []int {
+	if cap(b) < n {
+		return make([]int, n)
+	}
+	return b[:n]
+}
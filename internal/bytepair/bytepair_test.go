@@ -0,0 +1,167 @@
+package bytepair
+
+import (
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// naive is the straightforward quadratic merge: repeatedly merge the
+// leftmost adjacent pair with the lowest rank.
+func naive(ranks map[string]int, piece string) []int {
+	var parts []string
+	for i := range len(piece) {
+		parts = append(parts, piece[i:i+1])
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			if r, ok := ranks[parts[i]+parts[i+1]]; ok && (best < 0 || r < bestRank) {
+				best, bestRank = i, r
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = slices.Delete(parts, best+1, best+2)
+	}
+	var tokens []int
+	for _, p := range parts {
+		if r, ok := ranks[p]; ok {
+			tokens = append(tokens, r)
+		}
+	}
+	return tokens
+}
+
+// train builds a byte pair vocabulary by repeatedly merging the most
+// frequent adjacent pair of corpus, as BPE training does.
+func train(corpus string, merges int) map[string]int {
+	ranks := make(map[string]int)
+	var parts []string
+	for i := range len(corpus) {
+		if _, ok := ranks[corpus[i:i+1]]; !ok {
+			ranks[corpus[i:i+1]] = len(ranks)
+		}
+		parts = append(parts, corpus[i:i+1])
+	}
+	for range merges {
+		counts := make(map[string]int)
+		best := ""
+		for i := 0; i < len(parts)-1; i++ {
+			p := parts[i] + parts[i+1]
+			counts[p]++
+			if _, ok := ranks[p]; !ok && (counts[p] > counts[best] || counts[p] == counts[best] && p < best) {
+				best = p
+			}
+		}
+		if best == "" {
+			break
+		}
+		ranks[best] = len(ranks)
+		var merged []string
+		for i := 0; i < len(parts); i++ {
+			if i < len(parts)-1 && parts[i]+parts[i+1] == best {
+				merged = append(merged, best)
+				i++
+				continue
+			}
+			merged = append(merged, parts[i])
+		}
+		parts = merged
+	}
+	return ranks
+}
+
+func randomText(r *rand.Rand, alphabet string, n int) string {
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = alphabet[r.IntN(len(alphabet))]
+	}
+	return string(b)
+}
+
+func TestAppendMatchesNaive(t *testing.T) {
+	r := rand.New(rand.NewPCG(1, 2))
+	const alphabet = "abcab aab"
+	ranks := train(randomText(r, alphabet, 2000), 200)
+
+	// Remove some merged tokens so that merges can leave unknown parts.
+	sparse := make(map[string]int)
+	for tok, rank := range ranks {
+		if len(tok) == 1 || rank%7 != 0 {
+			sparse[tok] = rank
+		}
+	}
+	delete(sparse, "b")
+
+	for _, vocab := range []map[string]int{ranks, sparse} {
+		for range 500 {
+			piece := randomText(r, alphabet, 1+r.IntN(64))
+			got := Append(nil, vocab, piece)
+			want := naive(vocab, piece)
+			if !slices.Equal(got, want) {
+				t.Fatalf("Append(%q) = %v, want %v", piece, got, want)
+			}
+		}
+	}
+}
+
+func TestAppend(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2, "ab": 3, "bc": 4, "abc": 5}
+	tests := []struct {
+		piece string
+		want  []int
+	}{
+		{"", nil},
+		{"a", []int{0}},
+		{"abc", []int{5}},
+		{"abcabc", []int{5, 5}},
+		{"cab", []int{2, 3}},
+		{"bcab", []int{4, 3}},
+		{"xa", []int{0}},
+	}
+	for _, tt := range tests {
+		if got := Append(nil, ranks, tt.piece); !slices.Equal(got, tt.want) {
+			t.Errorf("Append(%q) = %v, want %v", tt.piece, got, tt.want)
+		}
+	}
+}
+
+func BenchmarkAppend(b *testing.B) {
+	r := rand.New(rand.NewPCG(1, 2))
+	ranks := train(randomText(r, "abcdefgh", 20000), 1000)
+	for _, n := range []int{16, 1024, 65536} {
+		piece := strings.Repeat(randomText(r, "abcdefgh", 16), n/16)
+		b.Run(strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			var dst []int
+			for i := 0; i < b.N; i++ {
+				dst = Append(dst[:0], ranks, piece)
+			}
+		})
+	}
+}
+
+func TestAppendOffsets(t *testing.T) {
+	ranks := map[string]int{"a": 0, "b": 1, "c": 2, "ab": 3, "bc": 4, "abc": 5}
+	tests := []struct {
+		piece   string
+		want    []int
+		offsets [][2]int
+	}{
+		{"", nil, nil},
+		{"abc", []int{5}, [][2]int{{10, 13}}},
+		{"cab", []int{2, 3}, [][2]int{{10, 11}, {11, 13}}},
+		{"xbc", []int{4}, [][2]int{{11, 13}}},
+	}
+	for _, tt := range tests {
+		got, offsets := AppendOffsets(nil, nil, ranks, tt.piece, 10)
+		if !slices.Equal(got, tt.want) || !slices.Equal(offsets, tt.offsets) {
+			t.Errorf("AppendOffsets(%q) = %v, %v, want %v, %v", tt.piece, got, offsets, tt.want, tt.offsets)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+// Package ignore implements gitignore pattern matching.
+//
+// Patterns follow the rules of gitignore(5): blank lines and lines
+// starting with # are skipped, a leading ! negates a pattern, a trailing
+// / matches only directories, and a pattern containing a / elsewhere is
+// matched relative to the directory of the file that holds it, while
+// others match a name at any depth below it. The wildcards *, ?, and
+// [...] do not match /, while ** matches across directories.
+package ignore
+
+import (
+	"bufio"
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// A Pattern is one pattern of an ignore file.
+type Pattern struct {
+	base    string // directory holding the pattern, slash-separated, "" for the root
+	re      *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// Parse returns the patterns in data, the contents of an ignore file in
+// the directory base. Base is slash-separated and relative to the root of
+// the tree being matched, with "" for the root itself.
+func Parse(base string, data []byte) []Pattern {
+	var patterns []Pattern
+	s := bufio.NewScanner(bytes.NewReader(data))
+	for s.Scan() {
+		if p, ok := ParsePattern(base, s.Text()); ok {
+			patterns = append(patterns, p)
+		}
+	}
+	return patterns
+}
+
+// ParsePattern parses a single line of an ignore file in the directory
+// base. It reports false if the line holds no pattern.
+func ParsePattern(base, line string) (Pattern, bool) {
+	line = strings.TrimSuffix(line, "\r")
+	line = trimTrailingSpace(line)
+	if line == "" || line[0] == '#' {
+		return Pattern{}, false
+	}
+	p := Pattern{base: base}
+	if line[0] == '!' {
+		p.negate = true
+		line = line[1:]
+	} else if line[0] == '\\' && len(line) > 1 && (line[1] == '#' || line[1] == '!') {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if line == "" {
+		return Pattern{}, false
+	}
+
+	// A pattern with a slash other than at the end is anchored to base.
+	anchored := strings.Contains(line, "/")
+	line = strings.TrimPrefix(line, "/")
+	expr := translate(line)
+	if !anchored {
+		expr = "(?:.*/)?" + expr
+	}
+	re, err := regexp.Compile("^" + expr + "$")
+	if err != nil {
+		return Pattern{}, false
+	}
+	p.re = re
+	return p, true
+}
+
+// trimTrailingSpace removes unescaped trailing spaces from line.
+func trimTrailingSpace(line string) string {
+	for strings.HasSuffix(line, " ") && !strings.HasSuffix(line, "\\ ") {
+		line = line[:len(line)-1]
+	}
+	return line
+}
+
+// translate converts a glob to a regular expression.
+func translate(glob string) string {
+	var b strings.Builder
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**") && i+2 == len(glob):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				b.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			b.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		case c == '\\' && i+1 < len(glob):
+			i++
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(glob[i : i+1]))
+		}
+	}
+	return b.String()
+}
+
+// match reports whether the pattern matches path, which is slash-separated
+// and relative to the root.
+func (p *Pattern) match(path string, isDir bool) bool {
+	if p.dirOnly && !isDir {
+		return false
+	}
+	if p.base != "" {
+		rel, ok := strings.CutPrefix(path, p.base+"/")
+		if !ok {
+			return false
+		}
+		path = rel
+	}
+	return p.re.MatchString(path)
+}
+
+// A Matcher is a list of patterns, in increasing order of precedence:
+// patterns from ignore files in deeper directories, and later patterns in
+// the same file, override earlier ones.
+type Matcher []Pattern
+
+// Match reports whether path, which is slash-separated and relative to
+// the root, is ignored. The last pattern that matches path decides.
+//
+// As with git, a path inside an ignored directory cannot be re-included;
+// callers walking a tree should not descend into ignored directories.
+func (m Matcher) Match(path string, isDir bool) bool {
+	for i := len(m) - 1; i >= 0; i-- {
+		if m[i].match(path, isDir) {
+			return !m[i].negate
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package ignore
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		patterns string
+		path     string
+		isDir    bool
+		want     bool
+	}{
+		{"*.log", "a.log", false, true},
+		{"*.log", "dir/sub/a.log", false, true},
+		{"*.log", "a.log.txt", false, false},
+		{"# comment\n\n*.log", "a.log", false, true},
+		{`\#file`, "#file", false, true},
+		{`\!file`, "!file", false, true},
+		{"trailing  ", "trailing", false, true},
+		{`space\ `, "space ", false, true},
+		{"build/", "build", true, true},
+		{"build/", "build", false, false},
+		{"build/", "src/build", true, true},
+		{"/build", "build", false, true},
+		{"/build", "src/build", false, false},
+		{"doc/*.txt", "doc/a.txt", false, true},
+		{"doc/*.txt", "doc/sub/a.txt", false, false},
+		{"doc/*.txt", "x/doc/a.txt", false, false},
+		{"**/foo", "foo", false, true},
+		{"**/foo", "a/b/foo", false, true},
+		{"**/foo/bar", "a/foo/bar", false, true},
+		{"a/**/b", "a/b", false, true},
+		{"a/**/b", "a/x/y/b", false, true},
+		{"a/**", "a/x/y", false, true},
+		{"a/**", "a", true, false},
+		{"?.go", "a.go", false, true},
+		{"?.go", "ab.go", false, false},
+		{"[abc].go", "b.go", false, true},
+		{"[!abc].go", "b.go", false, false},
+		{"[!abc].go", "d.go", false, true},
+		{"*.log\n!keep.log", "keep.log", false, false},
+		{"*.log\n!keep.log", "other.log", false, true},
+		{"!keep.log\n*.log", "keep.log", false, true},
+		{"*", "anything/at/all", false, true},
+	}
+	for _, tt := range tests {
+		m := Matcher(Parse("", []byte(tt.patterns)))
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("patterns %q: Match(%q, %v) = %v, want %v", tt.patterns, tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestMatchNested(t *testing.T) {
+	var m Matcher
+	m = append(m, Parse("", []byte("*.gen.go\n/vendor/\n"))...)
+	m = append(m, Parse("sub", []byte("!keep.gen.go\n/local.txt\n"))...)
+	tests := []struct {
+		path  string
+		isDir bool
+		want  bool
+	}{
+		{"a.gen.go", false, true},
+		{"sub/a.gen.go", false, true},
+		{"sub/keep.gen.go", false, false},
+		{"keep.gen.go", false, true},
+		{"vendor", true, true},
+		{"sub/vendor", true, false},
+		{"sub/local.txt", false, true},
+		{"local.txt", false, false},
+		{"sub/deeper/local.txt", false, false},
+	}
+	for _, tt := range tests {
+		if got := m.Match(tt.path, tt.isDir); got != tt.want {
+			t.Errorf("Match(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.want)
+		}
+	}
+}
+
+func TestParseSkipsEmpty(t *testing.T) {
+	for _, line := range []string{"", "   ", "# comment", "/", "!"} {
+		if p, ok := ParsePattern("", line); ok {
+			t.Errorf("ParsePattern(%q) = %+v, want no pattern", line, p)
+		}
+	}
+}
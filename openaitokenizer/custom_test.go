@@ -0,0 +1,97 @@
+package openaitokenizer
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// tinyRanks is a small vocabulary in tiktoken's rank file format.
+const tinyRanks = `YQ== 0
+Yg== 1
+Yw== 2
+IA== 3
+YWI= 4
+YWJj 5
+IGFiYw== 6
+`
+
+func TestNewEncoderFromFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"tiny.tiktoken": {Data: []byte(tinyRanks)},
+	}
+	enc, err := NewEncoderFromFS(fsys, "tiny.tiktoken", patterns["r50k_base"], map[string]int{"<|end|>": 7})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "abc abc cab"
+	if got, want := enc.Encode(text), []int{5, 6, 3, 2, 4}; !slices.Equal(got, want) {
+		t.Errorf("Encode(%q) = %v, want %v", text, got, want)
+	}
+	got, err := enc.EncodeWithSpecial("ab<|end|>", []string{AllSpecial}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{4, 7}; !slices.Equal(got, want) {
+		t.Errorf("EncodeWithSpecial = %v, want %v", got, want)
+	}
+	if s, err := enc.Decode([]int{6, 7}); err != nil || s != " abc<|end|>" {
+		t.Errorf("Decode = %q, %v, want %q", s, err, " abc<|end|>")
+	}
+
+	if _, err := NewEncoderFromFS(fsys, "missing.tiktoken", patterns["r50k_base"], nil); err == nil {
+		t.Error("NewEncoderFromFS with missing file succeeded, want error")
+	}
+}
+
+func TestNewEncoderFromReaderErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		ranks   string
+		pattern string
+		special map[string]int
+	}{
+		{"bad base64", "!!! 0\n", `\S+`, nil},
+		{"bad rank", "YQ== x\n", `\S+`, nil},
+		{"extra field", "YQ== 0 1\n", `\S+`, nil},
+		{"bad pattern", tinyRanks, `(?=a)`, nil},
+		{"special collides", tinyRanks, `\S+`, map[string]int{"<|end|>": 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewEncoderFromReader(strings.NewReader(tt.ranks), tt.pattern, tt.special); err == nil {
+				t.Error("NewEncoderFromReader succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestRegister(t *testing.T) {
+	enc, err := NewEncoderFromReader(strings.NewReader(tinyRanks), patterns["r50k_base"], nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	Register("test_tiny", enc)
+	t.Cleanup(func() { Unregister("test_tiny") })
+
+	got, err := NewEncoder("test_tiny")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := got.Count("abc abc"); n != 2 {
+		t.Errorf("Count = %d, want 2", n)
+	}
+
+	for _, name := range []string{"test_tiny", "cl100k_base"} {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Register(%q) did not panic", name)
+				}
+			}()
+			Register(name, enc)
+		}()
+	}
+}
@@ -19,4 +19,13 @@
 //	}
 //	count := enc.Count("Hello, world!")
 //	tokens := enc.Encode("Hello, world!")
+//	text, err := enc.Decode(tokens)
+//
+// Encode treats special tokens such as <|endoftext|> as ordinary text.
+// EncodeWithSpecial recognizes them using tiktoken's allowed and disallowed
+// special token semantics.
+//
+// Custom vocabularies in tiktoken's rank file format can be loaded with
+// NewEncoderFromReader or NewEncoderFromFS and made available by name
+// with Register.
 package openaitokenizer
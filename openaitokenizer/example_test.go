@@ -3,6 +3,7 @@ package openaitokenizer_test
 import (
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/tmc/tokencount/openaitokenizer"
 )
@@ -53,3 +54,78 @@ func ExampleEncoder_Encode() {
 	fmt.Printf("%d tokens: %v\n", len(tokens), tokens)
 	// Output: 1 tokens: [13225]
 }
+
+func ExampleEncoder_Decode() {
+	enc, err := openaitokenizer.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text, err := enc.Decode([]int{13225, 11, 2375, 0})
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(text)
+	// Output: Hello, world!
+}
+
+func ExampleEncoder_EncodeWithSpecial() {
+	enc, err := openaitokenizer.NewEncoder("cl100k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Allow every special token, as when building a prompt
+	tokens, err := enc.EncodeWithSpecial("Hi<|endoftext|>", []string{openaitokenizer.AllSpecial}, nil)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(tokens)
+
+	// Reject special tokens in untrusted input
+	_, err = enc.EncodeWithSpecial("Hi<|endoftext|>", nil, []string{openaitokenizer.AllSpecial})
+	fmt.Println(err)
+	// Output:
+	// [13347 100257]
+	// disallowed special token "<|endoftext|>" at offset 2
+}
+
+func ExampleEncoder_EncodeWithOffsets() {
+	enc, err := openaitokenizer.NewEncoder("o200k_base")
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	text := "Hello, world!"
+	_, offsets := enc.EncodeWithOffsets(text)
+	for _, off := range offsets {
+		fmt.Printf("%q\n", text[off[0]:off[1]])
+	}
+	// Output:
+	// "Hello"
+	// ","
+	// " world"
+	// "!"
+}
+
+func ExampleNewEncoderFromReader() {
+	// A tiny vocabulary in tiktoken's rank file format
+	ranks := strings.NewReader("YQ== 0\nYg== 1\nIA== 2\nYWI= 3\nIGFi 4\n")
+
+	enc, err := openaitokenizer.NewEncoderFromReader(ranks,
+		`'(?:[sdmt]|ll|ve|re)| ?\p{L}++| ?\p{N}++| ?[^\s\p{L}\p{N}]++|\s++$|\s+(?!\S)|\s`,
+		map[string]int{"<|endoftext|>": 5})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Make it available to NewEncoder and bpe.NewEncoder
+	openaitokenizer.Register("tiny", enc)
+
+	enc, err = openaitokenizer.NewEncoder("tiny")
+	if err != nil {
+		log.Fatal(err)
+	}
+	fmt.Println(enc.Encode("ab ab ba"))
+	// Output: [3 4 2 1 0]
+}
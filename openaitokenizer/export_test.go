@@ -0,0 +1,9 @@
+package openaitokenizer
+
+// Unregister removes an encoding added with Register, so that tests that
+// register encodings can be run more than once.
+func Unregister(name string) {
+	registry.Lock()
+	defer registry.Unlock()
+	delete(registry.encoders, name)
+}
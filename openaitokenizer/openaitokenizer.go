@@ -12,10 +12,15 @@ import (
 	_ "embed"
 	"encoding/base64"
 	"fmt"
-	"regexp"
+	"io"
+	"io/fs"
+	"maps"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
 
+	"github.com/tmc/tokencount/internal/bytepair"
 	"golang.org/x/tools/txtar"
 )
 
@@ -25,12 +30,62 @@ var vocabData []byte
 // An Encoder tokenizes text using byte pair encoding.
 type Encoder struct {
 	vocab   map[string]int
-	pattern *regexp.Regexp
+	decoder map[int]string
+	special map[string]int
+	split   *splitter
+}
+
+// An UnknownTokenError reports a token ID that is not in the encoder's vocabulary.
+type UnknownTokenError struct {
+	Token int // the unknown token ID
+	Index int // position of the token in the input slice
+}
+
+func (e *UnknownTokenError) Error() string {
+	return fmt.Sprintf("unknown token ID %d at index %d", e.Token, e.Index)
+}
+
+// encoders holds a loader for each embedded encoding. Each encoding is
+// parsed on first use and its tables are shared by every Encoder created
+// for it afterwards; Encoders never modify them.
+var encoders = make(map[string]func() (*Encoder, error))
+
+func init() {
+	for name := range patterns {
+		encoders[name] = sync.OnceValues(func() (*Encoder, error) {
+			return loadEncoder(name)
+		})
+	}
 }
 
 // NewEncoder returns a new encoder for the named encoding.
-// Supported encodings: o200k_base, cl100k_base, p50k_base, r50k_base.
+// Supported encodings: o200k_base, cl100k_base, p50k_base, r50k_base,
+// and any encoding added with Register.
+//
+// The vocabulary is parsed once per process and shared, so repeated calls
+// are cheap. The returned Encoder is safe for concurrent use.
 func NewEncoder(name string) (*Encoder, error) {
+	load, ok := encoders[name]
+	if !ok {
+		registry.RLock()
+		enc, ok := registry.encoders[name]
+		registry.RUnlock()
+		if !ok {
+			return nil, fmt.Errorf("unknown encoding %q", name)
+		}
+		e := *enc
+		return &e, nil
+	}
+	shared, err := load()
+	if err != nil {
+		return nil, err
+	}
+	e := *shared
+	return &e, nil
+}
+
+// loadEncoder parses the named encoding from the embedded archive.
+func loadEncoder(name string) (*Encoder, error) {
 	// Decompress the gzipped txtar archive
 	gr, err := gzip.NewReader(bytes.NewReader(vocabData))
 	if err != nil {
@@ -58,25 +113,61 @@ func NewEncoder(name string) (*Encoder, error) {
 		return nil, fmt.Errorf("unknown encoding %q", name)
 	}
 
+	return newEncoder(bytes.NewReader(data), patterns[name], specialTokens[name])
+}
+
+// NewEncoderFromReader returns an encoder for a custom vocabulary read from r
+// in tiktoken's rank file format: one base64-encoded token and its rank per line.
+//
+// Pattern is the pre-tokenization pattern in tiktoken's regular expression
+// syntax. Possessive quantifiers and the \s+(?!\S) lookahead used by
+// tiktoken's own patterns are supported; other lookaround assertions are not.
+// Special maps special token text to token IDs and may be nil.
+//
+// Use Register to make the encoder available by name.
+func NewEncoderFromReader(r io.Reader, pattern string, special map[string]int) (*Encoder, error) {
+	return newEncoder(r, pattern, maps.Clone(special))
+}
+
+// NewEncoderFromFS is like NewEncoderFromReader but reads the rank file
+// with the given name from fsys.
+func NewEncoderFromFS(fsys fs.FS, name, pattern string, special map[string]int) (*Encoder, error) {
+	f, err := fsys.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	e, err := NewEncoderFromReader(f, pattern, special)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return e, nil
+}
+
+// newEncoder parses a tiktoken rank file from r and builds an Encoder.
+func newEncoder(r io.Reader, pattern string, special map[string]int) (*Encoder, error) {
 	e := &Encoder{
-		vocab: make(map[string]int),
+		vocab:   make(map[string]int),
+		special: special,
 	}
 
 	// Parse tiktoken format: base64token rank
-	s := bufio.NewScanner(bytes.NewReader(data))
-	for s.Scan() {
-		line := s.Text()
-		fields := strings.Fields(line)
-		if len(fields) != 2 {
+	s := bufio.NewScanner(r)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 {
 			continue
 		}
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: want token and rank, got %d fields", line, len(fields))
+		}
 		tokenBytes, err := base64.StdEncoding.DecodeString(fields[0])
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("line %d: invalid token: %w", line, err)
 		}
 		rank, err := strconv.Atoi(fields[1])
 		if err != nil {
-			continue
+			return nil, fmt.Errorf("line %d: invalid rank: %w", line, err)
 		}
 		e.vocab[string(tokenBytes)] = rank
 	}
@@ -84,70 +175,148 @@ func NewEncoder(name string) (*Encoder, error) {
 		return nil, err
 	}
 
-	// Pattern splits on word boundaries, whitespace, and punctuation
-	e.pattern = regexp.MustCompile(
-		`'[sStTdDmM]|'[rR][eE]|'[vV][eE]|'[lL][lL]|[^\r\n\p{L}\p{N}]?\p{L}+|\p{N}{1,3}| ?[^\s\p{L}\p{N}]+[\r\n]*|\s*[\r\n]+|\s+`)
+	// Invert the vocabulary and special tokens for decoding
+	e.decoder = make(map[int]string, len(e.vocab)+len(e.special))
+	for tok, rank := range e.vocab {
+		e.decoder[rank] = tok
+	}
+	for tok, id := range e.special {
+		if prev, ok := e.decoder[id]; ok {
+			return nil, fmt.Errorf("special token %q has ID %d, already used by %q", tok, id, prev)
+		}
+		e.decoder[id] = tok
+	}
+
+	// Each encoding has its own pre-tokenization pattern
+	var err error
+	e.split, err = compilePattern(pattern)
+	if err != nil {
+		return nil, err
+	}
 
 	return e, nil
 }
 
+// registry holds encoders added with Register.
+var registry struct {
+	sync.RWMutex
+	encoders map[string]*Encoder
+}
+
+// Register makes enc available to NewEncoder, and so to bpe.NewEncoder,
+// under the given name. It is typically used with NewEncoderFromReader or
+// NewEncoderFromFS to add a custom vocabulary.
+// If Register is called twice with the same name, if name is the name of
+// an embedded encoding, or if enc is nil, it panics.
+func Register(name string, enc *Encoder) {
+	if enc == nil {
+		panic("openaitokenizer: Register encoder is nil")
+	}
+	if _, ok := encoders[name]; ok {
+		panic("openaitokenizer: Register called for embedded encoding " + name)
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, dup := registry.encoders[name]; dup {
+		panic("openaitokenizer: Register called twice for encoding " + name)
+	}
+	if registry.encoders == nil {
+		registry.encoders = make(map[string]*Encoder)
+	}
+	registry.encoders[name] = enc
+}
+
+// Encodings returns a sorted list of the names of the embedded encodings
+// and of the encodings added with Register.
+func Encodings() []string {
+	names := slices.Collect(maps.Keys(encoders))
+	registry.RLock()
+	for name := range registry.encoders {
+		names = append(names, name)
+	}
+	registry.RUnlock()
+	slices.Sort(names)
+	return names
+}
+
 // Encode returns the token IDs for the given text.
+// Special tokens are encoded as ordinary text; use EncodeWithSpecial
+// to recognize them.
 func (e *Encoder) Encode(text string) []int {
 	var tokens []int
 
-	// Split text into chunks using the pattern
-	for _, chunk := range e.pattern.FindAllString(text, -1) {
+	// Split text into chunks using the encoding's pattern
+	for start, end := range e.split.all(text) {
 		// Apply BPE to each chunk
-		tokens = append(tokens, e.encodeChunk([]byte(chunk))...)
+		tokens = bytepair.Append(tokens, e.vocab, text[start:end])
 	}
 
 	return tokens
 }
 
-// encodeChunk applies BPE to a single chunk of bytes.
-func (e *Encoder) encodeChunk(chunk []byte) []int {
-	if len(chunk) == 0 {
-		return nil
-	}
-
-	// Start with individual bytes
-	parts := make([][]byte, len(chunk))
-	for i := range chunk {
-		parts[i] = chunk[i : i+1]
+// EncodeWithOffsets is like Encode but also returns the byte offsets of
+// each token: token i was produced from text[offsets[i][0]:offsets[i][1]].
+// A token may cover part of a multi-byte UTF-8 character.
+func (e *Encoder) EncodeWithOffsets(text string) ([]int, [][2]int) {
+	var tokens []int
+	var offsets [][2]int
+	for start, end := range e.split.all(text) {
+		tokens, offsets = bytepair.AppendOffsets(tokens, offsets, e.vocab, text[start:end], start)
 	}
+	return tokens, offsets
+}
 
-	// Repeatedly merge the best pair until no more merges possible
-	for len(parts) > 1 {
-		bestIdx := -1
-		bestRank := int(^uint(0) >> 1) // max int
-
-		// Find the pair with the lowest rank in vocab
-		for i := 0; i < len(parts)-1; i++ {
-			pair := string(append(parts[i], parts[i+1]...))
-			if rank, ok := e.vocab[pair]; ok && rank < bestRank {
-				bestRank = rank
-				bestIdx = i
-			}
-		}
+// lookahead is the number of bytes that must follow a chunk before its
+// bounds are certain. Split patterns look at most a few characters past
+// the end of a chunk, for contraction suffixes and whitespace lookahead.
+const lookahead = 16
 
-		if bestIdx == -1 {
-			break // No more pairs to merge
+// CountPrefix counts the tokens in a prefix of text whose tokenization
+// cannot be changed by appending more text. It returns the number of
+// tokens and the length of the prefix in bytes. Counting the rest of the
+// text, followed by any text appended later, completes the count.
+//
+// CountPrefix lets a stream be counted incrementally; see bpe.Writer.
+func (e *Encoder) CountPrefix(text string) (n, size int) {
+	var tokens []int
+	for start, end := range e.split.all(text) {
+		if end+lookahead > len(text) {
+			break
 		}
+		tokens = bytepair.Append(tokens[:0], e.vocab, text[start:end])
+		n += len(tokens)
+		size = end
+	}
+	return n, size
+}
 
-		// Merge the best pair
-		merged := append(parts[bestIdx], parts[bestIdx+1]...)
-		parts = append(parts[:bestIdx], append([][]byte{merged}, parts[bestIdx+2:]...)...)
+// Decode returns the text for the given token IDs.
+// Byte sequences that are not valid UTF-8, such as a multi-byte character
+// split across a truncated token sequence, are replaced with U+FFFD.
+// Use DecodeBytes to obtain the raw bytes.
+// If a token ID is not in the vocabulary, Decode returns an *UnknownTokenError.
+func (e *Encoder) Decode(tokens []int) (string, error) {
+	b, err := e.DecodeBytes(tokens)
+	if err != nil {
+		return "", err
 	}
+	return string(bytes.ToValidUTF8(b, []byte("\uFFFD"))), nil
+}
 
-	// Convert parts to token IDs
-	tokens := make([]int, 0, len(parts))
-	for _, part := range parts {
-		if rank, ok := e.vocab[string(part)]; ok {
-			tokens = append(tokens, rank)
+// DecodeBytes returns the bytes for the given token IDs.
+// Unlike Decode, it does not require the result to be valid UTF-8,
+// so it can be used to decode partial token sequences.
+// If a token ID is not in the vocabulary, DecodeBytes returns an *UnknownTokenError.
+func (e *Encoder) DecodeBytes(tokens []int) ([]byte, error) {
+	var b []byte
+	for i, id := range tokens {
+		tok, ok := e.decoder[id]
+		if !ok {
+			return nil, &UnknownTokenError{Token: id, Index: i}
 		}
+		b = append(b, tok...)
 	}
-
-	return tokens
+	return b, nil
 }
 
 // Count returns the number of tokens in the text.
@@ -1,6 +1,14 @@
 package openaitokenizer
 
-import "testing"
+import (
+	"errors"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"unicode/utf8"
+)
 
 func TestEncoder(t *testing.T) {
 	enc, err := NewEncoder("o200k_base")
@@ -67,3 +75,239 @@ func TestUnknownEncoding(t *testing.T) {
 		t.Error("NewEncoder(nonexistent) should return error")
 	}
 }
+
+func TestDecode(t *testing.T) {
+	for _, name := range []string{"o200k_base", "cl100k_base", "p50k_base", "r50k_base"} {
+		t.Run(name, func(t *testing.T) {
+			enc, err := NewEncoder(name)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			texts := []string{
+				"",
+				"hello world",
+				"The quick brown fox jumps over the lazy dog.",
+				"Unicode: héllo, 世界, 🎉",
+				"  leading and trailing  \n\n",
+			}
+			for _, text := range texts {
+				got, err := enc.Decode(enc.Encode(text))
+				if err != nil {
+					t.Errorf("Decode(Encode(%q)) error: %v", text, err)
+					continue
+				}
+				if got != text {
+					t.Errorf("Decode(Encode(%q)) = %q", text, got)
+				}
+			}
+		})
+	}
+}
+
+func TestDecodeBytesPartial(t *testing.T) {
+	enc, err := NewEncoder("cl100k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "🎉"
+	tokens := enc.Encode(text)
+	if len(tokens) < 2 {
+		t.Skipf("Encode(%q) = %v, need a multi-token character", text, tokens)
+	}
+
+	b, err := enc.DecodeBytes(tokens[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text, string(b)) {
+		t.Errorf("DecodeBytes(%v) = %q, want prefix of %q", tokens[:1], b, text)
+	}
+
+	s, err := enc.Decode(tokens[:1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !utf8.ValidString(s) {
+		t.Errorf("Decode(%v) = %q, want valid UTF-8", tokens[:1], s)
+	}
+}
+
+func TestDecodeUnknownToken(t *testing.T) {
+	enc, err := NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := append(enc.Encode("hello"), -1)
+	_, err = enc.Decode(tokens)
+	var unknown *UnknownTokenError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("Decode(%v) error = %v, want *UnknownTokenError", tokens, err)
+	}
+	if unknown.Token != -1 || unknown.Index != len(tokens)-1 {
+		t.Errorf("UnknownTokenError = %+v, want Token -1 at index %d", unknown, len(tokens)-1)
+	}
+}
+
+func TestEncodeKnownTokens(t *testing.T) {
+	// Token IDs produced by tiktoken.
+	tests := []struct {
+		encoding string
+		text     string
+		want     []int
+	}{
+		{"r50k_base", "hello world", []int{31373, 995}},
+		{"cl100k_base", "hello world", []int{15339, 1917}},
+		{"cl100k_base", "tiktoken is great!", []int{83, 1609, 5963, 374, 2294, 0}},
+		{"o200k_base", "hello world", []int{24912, 2375}},
+		{"o200k_base", "Hello, world!", []int{13225, 11, 2375, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding+"/"+tt.text, func(t *testing.T) {
+			enc, err := NewEncoder(tt.encoding)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := enc.Encode(tt.text); !slices.Equal(got, tt.want) {
+				t.Errorf("Encode(%q) = %v, want %v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewEncoderShared(t *testing.T) {
+	const n = 8
+	encs := make([]*Encoder, n)
+	errs := make([]error, n)
+	var wg sync.WaitGroup
+	for i := range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			encs[i], errs[i] = NewEncoder("cl100k_base")
+		}()
+	}
+	wg.Wait()
+
+	for i := range n {
+		if errs[i] != nil {
+			t.Fatalf("NewEncoder: %v", errs[i])
+		}
+		if reflect.ValueOf(encs[i].vocab).UnsafePointer() != reflect.ValueOf(encs[0].vocab).UnsafePointer() {
+			t.Errorf("encoder %d has its own vocabulary, want shared", i)
+		}
+	}
+	if encs[0] == encs[1] {
+		t.Error("NewEncoder returned the same *Encoder twice")
+	}
+}
+
+func BenchmarkNewEncoder(b *testing.B) {
+	if _, err := NewEncoder("o200k_base"); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		if _, err := NewEncoder("o200k_base"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncode(b *testing.B) {
+	enc, err := NewEncoder("o200k_base")
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	inputs := map[string]string{
+		"prose":   strings.Repeat("The quick brown fox jumps over the lazy dog. ", 100),
+		"base64":  strings.Repeat("QmFzZTY0IGlzIGEgYmluYXJ5LXRvLXRleHQgZW5jb2Rpbmc", 100),
+		"letters": strings.Repeat("abcdefghij", 1000),
+	}
+	for name, text := range inputs {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(text)))
+			for i := 0; i < b.N; i++ {
+				enc.Encode(text)
+			}
+		})
+	}
+}
+
+func TestCountPrefix(t *testing.T) {
+	texts := []string{
+		"don't DON'T we'll they've I'm   spaces\t\tand\r\n\r\nnewlines",
+		"numbers 1234567890 and CamelCaseWords with 全角　spaces",
+		"trailing whitespace   ",
+	}
+	for _, name := range []string{"o200k_base", "cl100k_base", "r50k_base"} {
+		enc, err := NewEncoder(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, text := range texts {
+			want := enc.Count(text)
+			for i := 0; i <= len(text); i++ {
+				n, size := enc.CountPrefix(text[:i])
+				if got := n + enc.Count(text[size:]); got != want {
+					t.Fatalf("%s: CountPrefix(%q) = %d, %d; with rest counts %d tokens, want %d", name, text[:i], n, size, got, want)
+				}
+			}
+		}
+	}
+}
+
+func TestEncodeWithOffsets(t *testing.T) {
+	enc, err := NewEncoder("cl100k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, text := range []string{
+		"",
+		"hello world",
+		"Unicode: héllo, 世界, 🎉   \n\n end",
+	} {
+		tokens, offsets := enc.EncodeWithOffsets(text)
+		if !slices.Equal(tokens, enc.Encode(text)) {
+			t.Errorf("EncodeWithOffsets(%q) tokens = %v, want %v", text, tokens, enc.Encode(text))
+		}
+		if len(offsets) != len(tokens) {
+			t.Fatalf("EncodeWithOffsets(%q) returned %d offsets for %d tokens", text, len(offsets), len(tokens))
+		}
+		pos := 0
+		for i, off := range offsets {
+			if off[0] != pos {
+				t.Errorf("EncodeWithOffsets(%q) token %d starts at %d, want %d", text, i, off[0], pos)
+			}
+			b, err := enc.DecodeBytes(tokens[i : i+1])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := text[off[0]:off[1]]; got != string(b) {
+				t.Errorf("EncodeWithOffsets(%q) token %d covers %q, want %q", text, i, got, b)
+			}
+			pos = off[1]
+		}
+		if pos != len(text) {
+			t.Errorf("EncodeWithOffsets(%q) offsets end at %d, want %d", text, pos, len(text))
+		}
+	}
+}
+
+func TestEncodings(t *testing.T) {
+	got := Encodings()
+	for _, want := range []string{"cl100k_base", "o200k_base", "p50k_base", "r50k_base"} {
+		if !slices.Contains(got, want) {
+			t.Errorf("Encodings() = %v, want %s included", got, want)
+		}
+	}
+	if !slices.IsSorted(got) {
+		t.Errorf("Encodings() = %v, want sorted", got)
+	}
+}
@@ -0,0 +1,185 @@
+package openaitokenizer
+
+import (
+	"fmt"
+	"iter"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+// patterns holds tiktoken's pre-tokenization pattern for each embedded encoding,
+// written exactly as tiktoken defines it. Patterns are translated to Go syntax
+// by compilePattern.
+var patterns = map[string]string{
+	"r50k_base":   `'(?:[sdmt]|ll|ve|re)| ?\p{L}++| ?\p{N}++| ?[^\s\p{L}\p{N}]++|\s++$|\s+(?!\S)|\s`,
+	"p50k_base":   `'(?:[sdmt]|ll|ve|re)| ?\p{L}++| ?\p{N}++| ?[^\s\p{L}\p{N}]++|\s++$|\s+(?!\S)|\s`,
+	"cl100k_base": `'(?i:[sdmt]|ll|ve|re)|[^\r\n\p{L}\p{N}]?+\p{L}++|\p{N}{1,3}+| ?[^\s\p{L}\p{N}]++[\r\n]*+|\s++$|\s*[\r\n]|\s+(?!\S)|\s`,
+	"o200k_base": strings.Join([]string{
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]*[\p{Ll}\p{Lm}\p{Lo}\p{M}]+(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`[^\r\n\p{L}\p{N}]?[\p{Lu}\p{Lt}\p{Lm}\p{Lo}\p{M}]+[\p{Ll}\p{Lm}\p{Lo}\p{M}]*(?i:'s|'t|'re|'ve|'m|'ll|'d)?`,
+		`\p{N}{1,3}`,
+		` ?[^\s\p{L}\p{N}]+[\r\n/]*`,
+		`\s*[\r\n]+`,
+		`\s+(?!\S)`,
+		`\s+`,
+	}, "|"),
+}
+
+// Go's \s only matches ASCII whitespace, while tiktoken's regex engine
+// matches the Unicode White_Space property. These expand to the same set.
+const (
+	whitespaceClass = `\t-\r\x{85}\p{Z}`
+	lookaheadGroup  = "lookahead"
+	lookaheadSuffix = `\s+(?!\S)`
+
+	// window is the length of text matched at a time by splitter.next.
+	window = 256
+)
+
+// A splitter divides text into the chunks that byte pair encoding is applied to.
+type splitter struct {
+	re *regexp.Regexp
+
+	// lookahead is the index of the subexpression that stands in for
+	// tiktoken's \s+(?!\S), or -1 if the pattern has none.
+	lookahead int
+}
+
+// compilePattern translates a tiktoken pre-tokenization pattern to Go syntax
+// and compiles it.
+//
+// Possessive quantifiers are made greedy, \s and \S are expanded to match
+// Unicode whitespace, and the \s+(?!\S) alternative, which Go's regexp cannot
+// express, is replaced by a capture group whose matches give back their last
+// whitespace character when followed by more text. Other lookaround
+// assertions are rejected.
+func compilePattern(pat string) (*splitter, error) {
+	var b strings.Builder
+	inClass := false
+	for i := 0; i < len(pat); {
+		rest := pat[i:]
+		switch {
+		case !inClass && strings.HasPrefix(rest, lookaheadSuffix):
+			next := rest[len(lookaheadSuffix):]
+			if next != "" && next[0] != '|' && next[0] != ')' {
+				return nil, fmt.Errorf("unsupported pattern %q: %s must end an alternative", pat, lookaheadSuffix)
+			}
+			fmt.Fprintf(&b, "(?P<%s>[%s]+)", lookaheadGroup, whitespaceClass)
+			i += len(lookaheadSuffix)
+			continue
+		case !inClass && (strings.HasPrefix(rest, "(?=") || strings.HasPrefix(rest, "(?!") ||
+			strings.HasPrefix(rest, "(?<=") || strings.HasPrefix(rest, "(?<!")):
+			return nil, fmt.Errorf("unsupported pattern %q: lookaround assertion at offset %d", pat, i)
+		}
+
+		c := pat[i]
+		switch {
+		case c == '\\' && i+1 < len(pat):
+			switch pat[i+1] {
+			case 's':
+				if inClass {
+					b.WriteString(whitespaceClass)
+				} else {
+					b.WriteString("[" + whitespaceClass + "]")
+				}
+			case 'S':
+				if inClass {
+					return nil, fmt.Errorf("unsupported pattern %q: \\S inside character class", pat)
+				}
+				b.WriteString("[^" + whitespaceClass + "]")
+			default:
+				n := 2
+				if j := strings.IndexByte(pat[i:], '}'); i+2 < len(pat) && pat[i+2] == '{' && j > 0 {
+					n = j + 1 // \p{L}, \x{85}
+				}
+				b.WriteString(pat[i : i+n])
+				i += n
+				continue
+			}
+			i += 2
+			continue
+		case c == '[' && !inClass:
+			inClass = true
+		case c == ']' && inClass:
+			inClass = false
+		case !inClass && (c == '?' || c == '+' || c == '*' || c == '}'):
+			b.WriteByte(c)
+			i++
+			// Possessive quantifiers never change the result for tiktoken
+			// patterns, so treat them as greedy.
+			if i < len(pat) && pat[i] == '+' {
+				i++
+			}
+			continue
+		}
+		b.WriteByte(c)
+		i++
+	}
+
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, fmt.Errorf("compile pattern %q: %w", pat, err)
+	}
+	return &splitter{re: re, lookahead: re.SubexpIndex(lookaheadGroup)}, nil
+}
+
+// next returns the bounds of the first chunk in text.
+// If text contains no chunk, next returns -1, -1.
+func (s *splitter) next(text string) (start, end int) {
+	// Go's regexp is much faster on short inputs, so match against a
+	// window of text first. The result stands unless the match came too
+	// close to the end of the window for the rest of the text to matter.
+	var loc []int
+	if len(text) > window {
+		loc = s.re.FindStringSubmatchIndex(text[:window])
+		if loc == nil || loc[1]+lookahead > window {
+			loc = nil
+		}
+	}
+	if loc == nil {
+		loc = s.re.FindStringSubmatchIndex(text)
+	}
+	if loc == nil {
+		return -1, -1
+	}
+	start, end = loc[0], loc[1]
+	if s.lookahead >= 0 && loc[2*s.lookahead] >= 0 && end < len(text) {
+		// \s+(?!\S) leaves the last whitespace character to the chunk that follows.
+		if _, size := utf8.DecodeLastRuneInString(text[start:end]); end-start > size {
+			end -= size
+		}
+	}
+	return start, end
+}
+
+// all returns an iterator over the bounds of the chunks of text, in order.
+func (s *splitter) all(text string) iter.Seq2[int, int] {
+	return func(yield func(start, end int) bool) {
+		for pos := 0; pos < len(text); {
+			start, end := s.next(text[pos:])
+			if start < 0 {
+				return
+			}
+			if end == start {
+				// Skip a character on an empty match, as tiktoken does.
+				_, size := utf8.DecodeRuneInString(text[pos+start:])
+				pos += start + size
+				continue
+			}
+			if !yield(pos+start, pos+end) {
+				return
+			}
+			pos += end
+		}
+	}
+}
+
+// split returns the chunks of text in order.
+func (s *splitter) split(text string) []string {
+	var chunks []string
+	for start, end := range s.all(text) {
+		chunks = append(chunks, text[start:end])
+	}
+	return chunks
+}
@@ -0,0 +1,59 @@
+package openaitokenizer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := []struct {
+		encoding string
+		text     string
+		want     []string
+	}{
+		{"r50k_base", "hello world", []string{"hello", " world"}},
+		{"r50k_base", "hello   world", []string{"hello", "  ", " world"}},
+		{"r50k_base", "trailing   ", []string{"trailing", "   "}},
+		{"r50k_base", "a\n\nb", []string{"a", "\n", "\n", "b"}},
+		{"r50k_base", "don't", []string{"don", "'t"}},
+		{"r50k_base", "DON'T", []string{"DON", "'", "T"}},
+		{"r50k_base", "1234567", []string{"1234567"}},
+		{"cl100k_base", "DON'T", []string{"DON", "'T"}},
+		{"cl100k_base", "1234567", []string{"123", "456", "7"}},
+		{"cl100k_base", "a\n\nb", []string{"a", "\n\n", "b"}},
+		{"cl100k_base", "x  \n  y", []string{"x", "  \n", " ", " y"}},
+		{"cl100k_base", "HelloWorld", []string{"HelloWorld"}},
+		{"cl100k_base", "a　　b", []string{"a", "　", "　b"}},
+		{"cl100k_base", "a\t\tb", []string{"a", "\t", "\tb"}},
+		{"o200k_base", "HelloWorld", []string{"Hello", "World"}},
+		{"o200k_base", "don't DON'T", []string{"don't", " DON'T"}},
+		{"o200k_base", "1234567", []string{"123", "456", "7"}},
+		{"o200k_base", "a/b//\nc", []string{"a", "/b", "//\n", "c"}},
+		{"o200k_base", "if (x) {\n    return y;\n}", []string{"if", " (", "x", ")", " {\n", "   ", " return", " y", ";\n", "}"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.encoding+"/"+tt.text, func(t *testing.T) {
+			s, err := compilePattern(patterns[tt.encoding])
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := s.split(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("split(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompilePatternUnsupported(t *testing.T) {
+	for _, pat := range []string{
+		`\w+(?=x)`,
+		`(?<!a)b`,
+		`\s+(?!\S)x`,
+		`[\S]`,
+	} {
+		if _, err := compilePattern(pat); err == nil {
+			t.Errorf("compilePattern(%q) succeeded, want error", pat)
+		}
+	}
+}
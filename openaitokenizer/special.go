@@ -0,0 +1,127 @@
+package openaitokenizer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// AllSpecial stands for every special token of an encoding when passed in the
+// allowed or disallowed set of EncodeWithSpecial, like tiktoken's "all".
+const AllSpecial = "all"
+
+// specialTokens holds tiktoken's special tokens for each embedded encoding.
+var specialTokens = map[string]map[string]int{
+	"r50k_base": {
+		"<|endoftext|>": 50256,
+	},
+	"p50k_base": {
+		"<|endoftext|>": 50256,
+	},
+	"cl100k_base": {
+		"<|endoftext|>":   100257,
+		"<|fim_prefix|>":  100258,
+		"<|fim_middle|>":  100259,
+		"<|fim_suffix|>":  100260,
+		"<|endofprompt|>": 100276,
+	},
+	"o200k_base": {
+		"<|endoftext|>":   199999,
+		"<|endofprompt|>": 200018,
+	},
+}
+
+// A DisallowedSpecialError reports text in the input of EncodeWithSpecial
+// that matches a disallowed special token.
+type DisallowedSpecialError struct {
+	Token  string // the special token text
+	Offset int    // byte offset of the token in the input
+}
+
+func (e *DisallowedSpecialError) Error() string {
+	return fmt.Sprintf("disallowed special token %q at offset %d", e.Token, e.Offset)
+}
+
+// SpecialTokens returns the encoding's special tokens and their IDs.
+// The returned map is a copy and may be modified by the caller.
+func (e *Encoder) SpecialTokens() map[string]int {
+	m := make(map[string]int, len(e.special))
+	for tok, id := range e.special {
+		m[tok] = id
+	}
+	return m
+}
+
+// EncodeWithSpecial returns the token IDs for text, handling special tokens
+// like tiktoken's encode.
+//
+// Occurrences of special tokens listed in allowed are encoded as single
+// special token IDs. If text contains a special token listed in disallowed,
+// EncodeWithSpecial returns a *DisallowedSpecialError. Special tokens in
+// neither set are encoded as ordinary text, as Encode does.
+//
+// Either set may contain AllSpecial to stand for every special token of the
+// encoding; for the disallowed set, tokens that are also allowed are excluded.
+// Passing nil for allowed and []string{AllSpecial} for disallowed matches
+// tiktoken's defaults.
+func (e *Encoder) EncodeWithSpecial(text string, allowed, disallowed []string) ([]int, error) {
+	allowedSet, err := e.specialSet(allowed, nil)
+	if err != nil {
+		return nil, err
+	}
+	disallowedSet, err := e.specialSet(disallowed, allowedSet)
+	if err != nil {
+		return nil, err
+	}
+
+	// Reject disallowed special tokens before encoding anything
+	var bad *DisallowedSpecialError
+	for tok := range disallowedSet {
+		if i := strings.Index(text, tok); i >= 0 && (bad == nil || i < bad.Offset) {
+			bad = &DisallowedSpecialError{Token: tok, Offset: i}
+		}
+	}
+	if bad != nil {
+		return nil, bad
+	}
+
+	var tokens []int
+	for text != "" {
+		// Find the leftmost allowed special token, preferring the longest
+		start, special := -1, ""
+		for tok := range allowedSet {
+			i := strings.Index(text, tok)
+			if i >= 0 && (start < 0 || i < start || i == start && len(tok) > len(special)) {
+				start, special = i, tok
+			}
+		}
+		if start < 0 {
+			tokens = append(tokens, e.Encode(text)...)
+			break
+		}
+		tokens = append(tokens, e.Encode(text[:start])...)
+		tokens = append(tokens, e.special[special])
+		text = text[start+len(special):]
+	}
+	return tokens, nil
+}
+
+// specialSet returns the set of special tokens named by names, expanding
+// AllSpecial to every special token not in exclude.
+func (e *Encoder) specialSet(names []string, exclude map[string]bool) (map[string]bool, error) {
+	set := make(map[string]bool)
+	for _, name := range names {
+		if name == AllSpecial {
+			for tok := range e.special {
+				if !exclude[tok] {
+					set[tok] = true
+				}
+			}
+			continue
+		}
+		if _, ok := e.special[name]; !ok {
+			return nil, fmt.Errorf("unknown special token %q", name)
+		}
+		set[name] = true
+	}
+	return set, nil
+}
@@ -0,0 +1,107 @@
+package openaitokenizer
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestEncodeWithSpecial(t *testing.T) {
+	enc, err := NewEncoder("cl100k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "hello <|endoftext|> world<|fim_prefix|>"
+	hello := enc.Encode("hello ")
+	world := enc.Encode(" world")
+
+	tests := []struct {
+		name       string
+		allowed    []string
+		disallowed []string
+		want       []int
+		wantErr    string // disallowed token, if an error is expected
+	}{
+		{
+			name: "none",
+			want: enc.Encode(text),
+		},
+		{
+			name:    "all allowed",
+			allowed: []string{AllSpecial},
+			want:    slices.Concat(hello, []int{100257}, world, []int{100258}),
+		},
+		{
+			name:    "some allowed",
+			allowed: []string{"<|endoftext|>"},
+			want:    slices.Concat(hello, []int{100257}, enc.Encode(" world<|fim_prefix|>")),
+		},
+		{
+			name:       "tiktoken default",
+			disallowed: []string{AllSpecial},
+			wantErr:    "<|endoftext|>",
+		},
+		{
+			name:       "allowed excluded from all disallowed",
+			allowed:    []string{"<|endoftext|>"},
+			disallowed: []string{AllSpecial},
+			wantErr:    "<|fim_prefix|>",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := enc.EncodeWithSpecial(text, tt.allowed, tt.disallowed)
+			if tt.wantErr != "" {
+				var dErr *DisallowedSpecialError
+				if !errors.As(err, &dErr) {
+					t.Fatalf("EncodeWithSpecial error = %v, want *DisallowedSpecialError", err)
+				}
+				if dErr.Token != tt.wantErr {
+					t.Errorf("DisallowedSpecialError.Token = %q, want %q", dErr.Token, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("EncodeWithSpecial = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEncodeWithSpecialUnknown(t *testing.T) {
+	enc, err := NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := enc.EncodeWithSpecial("x", []string{"<|fim_prefix|>"}, nil); err == nil {
+		t.Error("EncodeWithSpecial with special token from another encoding succeeded, want error")
+	}
+}
+
+func TestDecodeSpecial(t *testing.T) {
+	enc, err := NewEncoder("o200k_base")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := "<|endoftext|>hi"
+	tokens, err := enc.EncodeWithSpecial(text, []string{AllSpecial}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens[0] != 199999 {
+		t.Errorf("EncodeWithSpecial(%q) = %v, want first token 199999", text, tokens)
+	}
+	got, err := enc.Decode(tokens)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got != text {
+		t.Errorf("Decode(%v) = %q, want %q", tokens, got, text)
+	}
+}
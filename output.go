@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// A count is the result of counting one input.
+type count struct {
+	name string
+	err  error // set if the input could not be counted
+
+	lines  int // newlines, as counted by wc -l
+	words  int // whitespace-separated words, as counted by wc -w
+	chars  int // UTF-8 characters, as counted by wc -m
+	bytes  int
+	tokens []int // tokens in each encoding
+}
+
+// countText counts content, the contents of the named input, with each of
+// encs. The encoders count concurrently.
+func countText(name string, content []byte, encs []bpe.Counter) count {
+	c := count{
+		name:   name,
+		lines:  bytes.Count(content, []byte("\n")),
+		words:  len(bytes.Fields(content)),
+		chars:  utf8.RuneCount(content),
+		bytes:  len(content),
+		tokens: make([]int, len(encs)),
+	}
+	text := string(content)
+	var wg sync.WaitGroup
+	for i, enc := range encs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.tokens[i] = enc.Count(text)
+		}()
+	}
+	wg.Wait()
+	return c
+}
+
+// add adds the counts of c to t.
+func (t *count) add(c count) {
+	t.lines += c.lines
+	t.words += c.words
+	t.chars += c.chars
+	t.bytes += c.bytes
+	for i, n := range c.tokens {
+		t.tokens[i] += n
+	}
+}
+
+// columns selects the columns to print.
+type columns struct {
+	lines, words, chars, bytes bool // optional wc columns
+	encodings                  []string
+}
+
+// header returns the column headings, which are only printed when there
+// is more than one encoding.
+func (cols columns) header() []string {
+	var h []string
+	for _, col := range []struct {
+		show bool
+		name string
+	}{{cols.lines, "lines"}, {cols.words, "words"}, {cols.chars, "chars"}, {cols.bytes, "bytes"}} {
+		if col.show {
+			h = append(h, col.name)
+		}
+	}
+	h = append(h, cols.encodings...)
+	for _, name := range cols.encodings[1:] {
+		h = append(h, name+"/"+cols.encodings[0])
+	}
+	return h
+}
+
+// values returns the columns of c to print, in wc's order, followed by
+// the tokens in each encoding and, if there are several, the ratio of the
+// tokens in each later encoding to those in the first.
+func (cols columns) values(c count) []string {
+	var v []string
+	if cols.lines {
+		v = append(v, strconv.Itoa(c.lines))
+	}
+	if cols.words {
+		v = append(v, strconv.Itoa(c.words))
+	}
+	if cols.chars {
+		v = append(v, strconv.Itoa(c.chars))
+	}
+	if cols.bytes {
+		v = append(v, strconv.Itoa(c.bytes))
+	}
+	v = append(v, intStrings(c.tokens)...)
+	for _, n := range c.tokens[1:] {
+		if c.tokens[0] == 0 {
+			v = append(v, "-")
+			continue
+		}
+		v = append(v, strconv.FormatFloat(float64(n)/float64(c.tokens[0]), 'f', 2, 64))
+	}
+	return v
+}
+
+// intStrings returns the decimal forms of ns.
+func intStrings(ns []int) []string {
+	s := make([]string, len(ns))
+	for i, n := range ns {
+		s[i] = strconv.Itoa(n)
+	}
+	return s
+}
+
+// printCounts prints counts the way wc does: one line per input with the
+// selected columns right-aligned to a common width, followed by the name,
+// and a total line if there is more than one input. Inputs that could not
+// be counted are left out. With several encodings, a line of column
+// headings comes first, and columns are widened to fit them.
+func printCounts(w io.Writer, counts []count, cols columns) error {
+	many := len(counts) > 1
+	counts = slices.DeleteFunc(slices.Clone(counts), func(c count) bool { return c.err != nil })
+	if many {
+		total := count{name: "total", tokens: make([]int, len(cols.encodings))}
+		for _, c := range counts {
+			total.add(c)
+		}
+		counts = append(counts, total)
+	}
+
+	rows := make([][]string, len(counts))
+	width := 1
+	for i, c := range counts {
+		rows[i] = cols.values(c)
+		for _, v := range rows[i] {
+			width = max(width, len(v))
+		}
+	}
+	var header []string
+	if len(cols.encodings) > 1 {
+		header = cols.header()
+	}
+	widths := make([]int, len(cols.header()))
+	for i := range widths {
+		widths[i] = width
+		if header != nil {
+			widths[i] = max(width, len(header[i]))
+		}
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		for i, v := range row {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			fmt.Fprintf(&b, "%*s", widths[i], v)
+		}
+	}
+	if header != nil {
+		writeRow(header)
+		b.WriteByte('\n')
+	}
+	for i, c := range counts {
+		writeRow(rows[i])
+		b.WriteString(" " + c.name + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tmc/tokencount/bpe"
+)
+
+// runShow implements the show subcommand, which prints its input with the
+// boundaries between tokens marked.
+func runShow(args []string) error {
+	fs := flag.NewFlagSet("show", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tokencount show [-ids] [-color when] [-sep sep] [file ...]\n")
+		fs.PrintDefaults()
+	}
+	encFlags := addEncodingFlags(fs)
+	var r renderer
+	fs.BoolVar(&r.ids, "ids", false, "Print the token IDs under each line")
+	color := fs.String("color", "auto", "Mark tokens with colors: always, never, or auto (if stdout is a terminal)")
+	fs.StringVar(&r.sep, "sep", "|", "Separator between tokens when not using colors")
+	fs.Parse(args)
+
+	switch *color {
+	case "always":
+		r.color = true
+	case "never":
+	case "auto":
+		r.color = isTerminal(os.Stdout) && os.Getenv("NO_COLOR") == ""
+	default:
+		return fmt.Errorf("invalid -color %q: want always, never, or auto", *color)
+	}
+
+	enc, err := encFlags.encoder()
+	if err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"} // Use stdin if no files specified
+	}
+
+	w := bufio.NewWriter(os.Stdout)
+	for i, file := range files {
+		content, err := readInput(file)
+		if err != nil {
+			return err
+		}
+		if len(files) > 1 {
+			if i > 0 {
+				w.WriteString("\n")
+			}
+			fmt.Fprintf(w, "==> %s <==\n", file)
+		}
+		r.render(w, tokenSpans(enc, string(content)))
+	}
+	return w.Flush()
+}
+
+// isTerminal reports whether f is a terminal.
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// A span is text covered by one token, or by several tokens that split a
+// UTF-8 character between them or that a character expands to, so that it
+// can be printed on its own.
+type span struct {
+	text string
+	ids  []int
+}
+
+// tokenSpans returns the spans of text tokenized by enc.
+func tokenSpans(enc bpe.Encoder, text string) []span {
+	tokens, offsets := enc.EncodeWithOffsets(text)
+	var spans []span
+	start := 0
+	var ids []int
+	for i, id := range tokens {
+		end := offsets[i][1]
+		if end <= start && ids == nil && len(spans) > 0 {
+			// Another token of a character that expands to several,
+			// such as "½" under NFKC normalization.
+			last := &spans[len(spans)-1]
+			last.ids = append(last.ids, id)
+			continue
+		}
+		ids = append(ids, id)
+		if end < len(text) && !utf8.RuneStart(text[end]) {
+			continue
+		}
+		spans = append(spans, span{text[start:end], ids})
+		start, ids = end, nil
+	}
+	if ids != nil {
+		spans = append(spans, span{text[start:], ids})
+	}
+	return spans
+}
+
+// palette lists the 256-color background colors of tokens, in turn.
+var palette = []int{153, 223, 194, 217, 189, 229}
+
+// A renderer prints spans with their boundaries marked.
+type renderer struct {
+	color bool   // mark tokens with background colors
+	sep   string // otherwise, separate them with sep
+	ids   bool   // print the token IDs under each line
+}
+
+// render prints spans to w. Each span is marked by a color or preceded by
+// the separator, unless it starts a line. With ids set, every line is
+// followed by a line of token IDs, each under the start of its token, and
+// tokens are padded to the width of their IDs.
+func (r *renderer) render(w io.Writer, spans []span) {
+	var line, ids strings.Builder
+	started := false // whether line has any tokens
+	flush := func() {
+		line.WriteString("\n")
+		io.WriteString(w, line.String())
+		if r.ids {
+			io.WriteString(w, strings.TrimRight(ids.String(), " ")+"\n")
+		}
+		line.Reset()
+		ids.Reset()
+		started = false
+	}
+
+	for i, s := range spans {
+		bg := palette[i%len(palette)]
+		label := idLabel(s.ids)
+		for j, part := range strings.Split(s.text, "\n") {
+			if j > 0 {
+				flush()
+				label = "" // the rest of the span continues on the next line
+			}
+			if part == "" && (label == "" || r.color && !r.ids) {
+				continue // nothing to show
+			}
+			width := utf8.RuneCountInString(part)
+			if r.ids {
+				width = max(width, len(label))
+				label += strings.Repeat(" ", width-len(label))
+			}
+			if started && !r.color {
+				line.WriteString(r.sep)
+				ids.WriteString(strings.Repeat(" ", utf8.RuneCountInString(r.sep)))
+			}
+			started = true
+
+			pad := strings.Repeat(" ", width-utf8.RuneCountInString(part))
+			if r.color {
+				fmt.Fprintf(&line, "\x1b[30;48;5;%dm%s%s\x1b[0m", bg, part, pad)
+				fmt.Fprintf(&ids, "\x1b[38;5;%dm%s\x1b[0m", bg, label)
+			} else {
+				line.WriteString(part + pad)
+				ids.WriteString(label)
+			}
+		}
+	}
+	if started {
+		flush()
+	}
+}
+
+// idLabel returns the IDs of a span joined by "+".
+func idLabel(ids []int) string {
+	return strings.Join(intStrings(ids), "+")
+}
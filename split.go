@@ -0,0 +1,164 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/tmc/tokencount/bpe/splitter"
+)
+
+// runSplit implements the split subcommand, which splits its input into
+// chunks of at most a number of tokens.
+func runSplit(args []string) error {
+	fs := flag.NewFlagSet("split", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: tokencount split [-max N] [-overlap M] [-type type] [-o dir] [file ...]\n")
+		fs.PrintDefaults()
+	}
+	encFlags := addEncodingFlags(fs)
+	max := fs.Int("max", 512, "Maximum tokens per chunk")
+	overlap := fs.Int("overlap", 0, "Tokens repeated from the end of the previous chunk")
+	dir := fs.String("o", "", "Write each chunk to a file in `dir` instead of JSONL to stdout")
+	kind := fs.String("type", "", "Input `type`: text, markdown, or go (default by file extension)")
+	fs.Parse(args)
+
+	enc, err := encFlags.encoder()
+	if err != nil {
+		return err
+	}
+	s, err := splitter.New(enc, *max, *overlap)
+	if err != nil {
+		return err
+	}
+
+	files := fs.Args()
+	if len(files) == 0 {
+		files = []string{"-"} // Use stdin if no files specified
+	}
+
+	if *dir != "" {
+		if err := checkChunkNames(files); err != nil {
+			return err
+		}
+	}
+
+	out := json.NewEncoder(os.Stdout)
+	for _, file := range files {
+		content, err := readInput(file)
+		if err != nil {
+			return err
+		}
+		chunks, err := splitInput(s, *kind, file, string(content))
+		if err != nil {
+			return err
+		}
+		if *dir != "" {
+			if err := writeChunks(*dir, file, chunks); err != nil {
+				return err
+			}
+			continue
+		}
+		for i, c := range chunks {
+			rec := struct {
+				File  string `json:"file"`
+				Index int    `json:"index"`
+				splitter.Chunk
+			}{file, i, c}
+			if err := out.Encode(rec); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// splitInput splits the content of the named file according to its type.
+// If kind is empty, the type is chosen by the file's extension.
+func splitInput(s *splitter.Splitter, kind, file, content string) ([]splitter.Chunk, error) {
+	if kind == "" {
+		switch filepath.Ext(file) {
+		case ".md", ".markdown":
+			kind = "markdown"
+		case ".go":
+			kind = "go"
+		default:
+			kind = "text"
+		}
+	}
+	switch kind {
+	case "text":
+		return s.Split(content), nil
+	case "markdown":
+		return s.SplitMarkdown(content), nil
+	case "go":
+		chunks, err := s.SplitGo(content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		return chunks, nil
+	}
+	return nil, fmt.Errorf("unknown input type %q", kind)
+}
+
+// readInput returns the contents of the named file, or of stdin for "-".
+func readInput(filename string) ([]byte, error) {
+	if filename == "-" {
+		content, err := io.ReadAll(os.Stdin)
+		if err != nil {
+			return nil, fmt.Errorf("error reading input: %w", err)
+		}
+		return content, nil
+	}
+	content, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file %s: %w", filename, err)
+	}
+	return content, nil
+}
+
+// writeChunks writes each chunk of the named file to its own file in dir,
+// named after the input and numbered like the index of JSONL records:
+// chunks of notes.md are notes-0000.md, notes-0001.md, and so on. Chunks of
+// stdin are chunk-0000.txt, chunk-0001.txt, and so on.
+func writeChunks(dir, file string, chunks []splitter.Chunk) error {
+	if err := os.MkdirAll(dir, 0o777); err != nil {
+		return err
+	}
+	base, ext := chunkName(file)
+	for i, c := range chunks {
+		name := filepath.Join(dir, fmt.Sprintf("%s-%04d%s", base, i, ext))
+		if err := os.WriteFile(name, []byte(c.Text), 0o666); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// chunkName returns the base name and extension of the chunk files of the
+// named input.
+func chunkName(file string) (base, ext string) {
+	if file == "-" {
+		return "chunk", ".txt"
+	}
+	ext = filepath.Ext(file)
+	return strings.TrimSuffix(filepath.Base(file), ext), ext
+}
+
+// checkChunkNames reports an error if two of files would write chunk files
+// with the same names, such as a/README.md and b/README.md.
+func checkChunkNames(files []string) error {
+	seen := make(map[string]string)
+	for _, file := range files {
+		base, ext := chunkName(file)
+		if prev, ok := seen[base+ext]; ok {
+			return fmt.Errorf("%s and %s would both write chunks named %s-NNNN%s", prev, file, base, ext)
+		}
+		seen[base+ext] = file
+	}
+	return nil
+}
@@ -1,53 +1,189 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
 	"os"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
 
 	"github.com/tmc/tokencount/bpe"
 )
 
 func main() {
-	if err := run(); err != nil {
+	switch err := run(); err {
+	case nil:
+	case errOverBudget:
+		os.Exit(3)
+	case errReported:
+		os.Exit(1)
+	default:
 		fmt.Fprintf(os.Stderr, "tokencount: %v\n", err)
 		os.Exit(1)
 	}
 }
 
+var (
+	// errReported is returned by run when some inputs could not be counted
+	// and the errors have already been reported.
+	errReported = errors.New("errors reported")
+
+	// errOverBudget is returned by run when inputs were counted but some
+	// were over their budget, which makes tokencount exit with status 3.
+	errOverBudget = errors.New("over budget")
+)
+
+// commands are the subcommands, by name.
+// Without a subcommand, tokencount counts the tokens in its input.
+var commands = map[string]func(args []string) error{
+	"decode": runDecode,
+	"encode": runEncode,
+	"show":   runShow,
+	"split":  runSplit,
+}
+
 func run() error {
-	encoding := flag.String("encoding", "anthropic", "Encoding to use (anthropic, o200k_base, cl100k_base, p50k_base, r50k_base)")
+	if len(os.Args) > 1 {
+		if cmd, ok := commands[os.Args[1]]; ok {
+			return cmd(os.Args[2:])
+		}
+	}
+
+	var err error
+	encFlags := addEncodingListFlags(flag.CommandLine)
 	verbose := flag.Bool("verbose", false, "Verbose output")
+	var cols columns
+	flag.BoolVar(&cols.lines, "l", false, "Also print the newline counts, like wc -l")
+	flag.BoolVar(&cols.words, "w", false, "Also print the word counts, like wc -w")
+	flag.BoolVar(&cols.chars, "m", false, "Also print the character counts, like wc -m")
+	flag.BoolVar(&cols.bytes, "c", false, "Also print the byte counts, like wc -c")
+	var walk walkOptions
+	flag.Var((*globs)(&walk.include), "include", "Count only files under directories that match `glob` (repeatable)")
+	flag.Var((*globs)(&walk.exclude), "exclude", "Skip files and directories that match `glob` (repeatable)")
+	flag.BoolVar(&walk.hidden, "hidden", false, "Include hidden files and directories under directories")
+	flag.BoolVar(&walk.noIgnore, "no-ignore", false, "Do not respect .gitignore and .ignore files")
+	maxSize := flag.String("max-size", "", "Skip files under directories larger than `size` (e.g. 512K, 1M)")
+	format := flag.String("format", "text", "Output `format` ("+strings.Join(formats, ", ")+")")
+	jobs := flag.Int("j", runtime.GOMAXPROCS(0), "Count up to `n` files at once")
+	var b budget
+	flag.IntVar(&b.max, "max", 0, "Exit with status 3 if any input has more than `n` tokens")
+	flag.IntVar(&b.maxTotal, "max-total", 0, "Exit with status 3 if all inputs have more than `n` tokens in total")
+	budgetFile := flag.String("budget", "", "Read per-path token limits from `file`, with a glob and a positive limit on each line")
 	flag.Parse()
 
-	enc, err := bpe.NewEncoder(*encoding)
-	if err != nil {
-		return fmt.Errorf("failed to get encoding: %w", err)
+	if *jobs < 1 {
+		return fmt.Errorf("-j must be at least 1")
+	}
+	if b.max < 0 || b.maxTotal < 0 {
+		return fmt.Errorf("-max and -max-total must not be negative")
+	}
+	if *budgetFile != "" {
+		if err := b.readBudgetFile(*budgetFile); err != nil {
+			return err
+		}
+	}
+
+	if !slices.Contains(formats, *format) {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	walk.verbose = *verbose
+	if walk.maxSize, err = parseSize(*maxSize); err != nil {
+		return err
+	}
+
+	if cols.encodings, err = encFlags.encodings(); err != nil {
+		return err
+	}
+	encs := make([]bpe.Counter, len(cols.encodings))
+	for i, name := range cols.encodings {
+		if encs[i], err = newEncoder(name); err != nil {
+			return err
+		}
 	}
 
 	files := flag.Args()
 	if len(files) == 0 {
 		files = []string{"-"} // Use stdin if no files specified
 	}
+	// Directories are replaced by the files under them.
+	inputs := expandInputs(files, walk)
 
-	for _, file := range files {
-		if err := processFile(file, enc, *verbose); err != nil {
-			return err
+	// An input that cannot be read does not stop the others from being
+	// counted. Text output reports the error on stderr; the structured
+	// formats record it with the input.
+	text := *format == "text"
+	counts := countFiles(inputs, encs, *jobs)
+	failed := false
+	for _, c := range counts {
+		switch {
+		case c.err != nil:
+			failed = true
+			if text {
+				fmt.Fprintf(os.Stderr, "tokencount: %v\n", c.err)
+			}
+		case *verbose && text:
+			fmt.Printf("Tokens in %s: %s\n", c.name, strings.Join(intStrings(c.tokens), ", "))
 		}
 	}
 
+	if err := writeCounts(os.Stdout, *format, counts, cols); err != nil {
+		return err
+	}
+	// Inputs that could not be counted may hide others over budget,
+	// so they take precedence.
+	over := b.check(os.Stderr, counts, cols.encodings)
+	switch {
+	case failed:
+		return errReported
+	case over:
+		return errOverBudget
+	}
 	return nil
 }
 
-func processFile(filename string, enc bpe.Counter, verbose bool) error {
+// countFiles counts inputs with encs, processing up to jobs of them at
+// once. The counts are in the order of inputs, whatever order they finish
+// in. Inputs that already have an error are not read.
+func countFiles(inputs []input, encs []bpe.Counter, jobs int) []count {
+	counts := make([]count, len(inputs))
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for range min(jobs, len(inputs)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				if in := inputs[i]; in.err != nil {
+					counts[i] = count{name: in.name, err: in.err}
+				} else {
+					counts[i] = processFile(in.name, encs)
+				}
+			}
+		}()
+	}
+	for i := range inputs {
+		next <- i
+	}
+	close(next)
+	wg.Wait()
+	return counts
+}
+
+// processFile counts the named file, or stdin if filename is "-", with
+// each of encs. If the file cannot be read, the error is set in the result.
+func processFile(filename string, encs []bpe.Counter) count {
 	var reader io.Reader
 	if filename == "-" {
 		reader = os.Stdin
 	} else {
 		file, err := os.Open(filename)
 		if err != nil {
-			return fmt.Errorf("failed to open file %s: %w", filename, err)
+			return count{name: filename, err: fmt.Errorf("failed to open file %s: %w", filename, err)}
 		}
 		defer file.Close()
 		reader = file
@@ -55,14 +191,97 @@ func processFile(filename string, enc bpe.Counter, verbose bool) error {
 
 	content, err := io.ReadAll(reader)
 	if err != nil {
-		return fmt.Errorf("error reading input: %w", err)
+		return count{name: filename, err: fmt.Errorf("error reading input: %w", err)}
 	}
 
-	totalTokens := enc.Count(string(content))
+	return countText(filename, content, encs)
+}
+
+// encodingFlags are the flags that select an encoding.
+type encodingFlags struct {
+	fs       *flag.FlagSet
+	encoding *string
+	model    *string
+	all      *bool // nil unless several encodings can be selected
+}
 
-	if verbose {
-		fmt.Printf("Tokens in %s: %d\n", filename, totalTokens)
+// addEncodingFlags defines the -encoding and -model flags on fs.
+func addEncodingFlags(fs *flag.FlagSet) *encodingFlags {
+	return &encodingFlags{
+		fs:       fs,
+		encoding: fs.String("encoding", "anthropic", "Encoding to use ("+strings.Join(bpe.Encodings(), ", ")+")"),
+		model:    fs.String("model", "", "Model name to select the encoding for (e.g. gpt-4o, claude-3-5-sonnet)"),
 	}
-	fmt.Printf("\t%d %s\n", totalTokens, filename)
-	return nil
+}
+
+// addEncodingListFlags is like addEncodingFlags, but -encoding takes a
+// comma-separated list of encodings, and it also defines -all to select
+// every encoding.
+func addEncodingListFlags(fs *flag.FlagSet) *encodingFlags {
+	f := addEncodingFlags(fs)
+	fs.Lookup("encoding").Usage = "Comma-separated `list` of encodings to use (" + strings.Join(bpe.Encodings(), ", ") + ")"
+	f.all = fs.Bool("all", false, "Use every encoding")
+	return f
+}
+
+// encoder returns the encoder selected by the flags, after they are parsed.
+// If the encoding only approximates the model's tokenizer, it prints a warning.
+func (f *encodingFlags) encoder() (bpe.Encoder, error) {
+	encodings, err := f.encodings()
+	if err != nil {
+		return nil, err
+	}
+	if len(encodings) > 1 {
+		return nil, fmt.Errorf("only one encoding can be used")
+	}
+	return newEncoder(encodings[0])
+}
+
+// encodings is like encoder, but returns the names of the selected
+// encodings, in the order given.
+func (f *encodingFlags) encodings() ([]string, error) {
+	explicit := false
+	f.fs.Visit(func(fl *flag.Flag) { explicit = explicit || fl.Name == "encoding" })
+
+	if f.all != nil && *f.all {
+		if explicit || *f.model != "" {
+			return nil, fmt.Errorf("cannot use -all with -model or -encoding")
+		}
+		return bpe.Encodings(), nil
+	}
+
+	if *f.model != "" {
+		if explicit {
+			return nil, fmt.Errorf("cannot use both -model and -encoding")
+		}
+		m, err := bpe.ForModel(*f.model)
+		if err != nil {
+			return nil, err
+		}
+		if m.Approximate {
+			fmt.Fprintf(os.Stderr, "tokencount: warning: counts for %s are approximate (%s encoding)\n", m.Name, m.Encoding)
+		}
+		return []string{m.Encoding}, nil
+	}
+
+	var names []string
+	for name := range strings.SplitSeq(*f.encoding, ",") {
+		name = strings.TrimSpace(name)
+		if name != "" && !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no encoding given")
+	}
+	return names, nil
+}
+
+// newEncoder returns the named encoder.
+func newEncoder(encoding string) (bpe.Encoder, error) {
+	enc, err := bpe.NewEncoder(encoding)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get encoding: %w", err)
+	}
+	return enc, nil
 }
@@ -2,8 +2,12 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"os"
 	"os/exec"
 	"path"
+	"strconv"
+	"strings"
 	"testing"
 
 	"rsc.io/script"
@@ -21,9 +25,12 @@ func TestTokencount(t *testing.T) {
 	delete(cmds, "exec") // Remove exec command
 	cmds["tokencount"] = script.Program(tokencountPath, nil, 0)
 	cmds["stdin-tokencount"] = stdinTokencountCmd(tokencountPath)
+	cmds["status-tokencount"] = statusTokencountCmd(tokencountPath)
+	conds := scripttest.DefaultConds()
+	conds["root"] = script.BoolCondition("running as root, who can read files without permission", os.Geteuid() == 0)
 	engine := &script.Engine{
 		Cmds:  cmds,
-		Conds: scripttest.DefaultConds(),
+		Conds: conds,
 	}
 	scripttest.Test(t, context.Background(), engine, []string{}, "testdata/*.txt")
 }
@@ -53,3 +60,42 @@ func stdinTokencountCmd(tokencountPath string) script.Cmd {
 		},
 	)
 }
+
+// statusTokencountCmd runs tokencount and checks that it exits with the status given as the first arg,
+// which the ! prefix cannot tell apart from other failures
+func statusTokencountCmd(tokencountPath string) script.Cmd {
+	return script.Command(
+		script.CmdUsage{
+			Summary: "run tokencount and check its exit status",
+			Args:    "status [args...]",
+		},
+		func(s *script.State, args ...string) (script.WaitFunc, error) {
+			if len(args) < 1 {
+				return nil, script.ErrUsage
+			}
+			want, err := strconv.Atoi(args[0])
+			if err != nil {
+				return nil, script.ErrUsage
+			}
+			cmd := exec.Command(tokencountPath, args[1:]...)
+			cmd.Dir = s.Getwd()
+			cmd.Env = s.Environ()
+			var stdout, stderr strings.Builder
+			cmd.Stdout, cmd.Stderr = &stdout, &stderr
+			got := 0
+			if err := cmd.Run(); err != nil {
+				exitErr, ok := err.(*exec.ExitError)
+				if !ok {
+					return nil, err
+				}
+				got = exitErr.ExitCode()
+			}
+			return func(*script.State) (string, string, error) {
+				if got != want {
+					return stdout.String(), stderr.String(), fmt.Errorf("exit status %d, want %d", got, want)
+				}
+				return stdout.String(), stderr.String(), nil
+			}, nil
+		},
+	)
+}
@@ -0,0 +1,216 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/tmc/tokencount/internal/ignore"
+)
+
+// walkOptions control which files are counted under a directory.
+type walkOptions struct {
+	include  []string // globs that files must match, if any
+	exclude  []string // globs of files and directories to skip
+	hidden   bool     // include files and directories whose names start with "."
+	noIgnore bool     // do not read .gitignore and .ignore files
+	maxSize  int64    // skip files larger than this many bytes, if positive
+	verbose  bool     // report skipped files on stderr
+}
+
+// ignoreFiles are the files whose patterns exclude paths from a walk.
+var ignoreFiles = []string{".gitignore", ".ignore"}
+
+// An input is a file to count. If err is set, the file was found by
+// walking a directory but could not be read.
+type input struct {
+	name string
+	err  error
+}
+
+// expandInputs returns files with each directory replaced by the files
+// under it, in lexical order. Files named explicitly are always kept.
+// Files and directories that cannot be read while walking are returned
+// with their errors, so that they are reported with the other inputs.
+func expandInputs(files []string, opts walkOptions) []input {
+	var expanded []input
+	for _, file := range files {
+		if file == "-" {
+			expanded = append(expanded, input{name: file})
+			continue
+		}
+		info, err := os.Stat(file)
+		if err != nil || !info.IsDir() {
+			// Let processFile report any error.
+			expanded = append(expanded, input{name: file})
+			continue
+		}
+		expanded = append(expanded, walkDir(file, opts)...)
+	}
+	return expanded
+}
+
+// walkDir returns the regular files under root that opts select.
+func walkDir(root string, opts walkOptions) []input {
+	var include, exclude ignore.Matcher
+	for _, glob := range opts.include {
+		p, _ := ignore.ParsePattern("", glob)
+		include = append(include, p)
+	}
+	for _, glob := range opts.exclude {
+		p, _ := ignore.ParsePattern("", glob)
+		exclude = append(exclude, p)
+	}
+
+	// ignored holds the patterns of the ignore files in each directory
+	// from the root down to the current one.
+	ignored := make(map[string]ignore.Matcher)
+
+	var files []input
+	filepath.WalkDir(root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// A directory that cannot be read is skipped.
+			files = append(files, input{name, fmt.Errorf("failed to read directory %s: %w", name, err)})
+			return nil
+		}
+		rel, err := filepath.Rel(root, name)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == "." {
+			if !opts.noIgnore {
+				ignored[rel] = readIgnoreFiles(name, "")
+			}
+			return nil
+		}
+
+		parent := ignored[path.Dir(rel)]
+		skip := d.Name() == ".git" ||
+			!opts.hidden && strings.HasPrefix(d.Name(), ".") ||
+			parent.Match(rel, d.IsDir()) ||
+			exclude.Match(rel, d.IsDir())
+		if d.IsDir() {
+			if skip {
+				return filepath.SkipDir
+			}
+			m := parent
+			if !opts.noIgnore {
+				m = append(m[:len(m):len(m)], readIgnoreFiles(name, rel)...)
+			}
+			ignored[rel] = m
+			return nil
+		}
+		if skip || !d.Type().IsRegular() || len(include) > 0 && !include.Match(rel, false) {
+			return nil
+		}
+
+		if reason, err := skipFile(name, opts.maxSize); err != nil {
+			files = append(files, input{name, fmt.Errorf("failed to read file %s: %w", name, err)})
+			return nil
+		} else if reason != "" {
+			if opts.verbose {
+				fmt.Fprintf(os.Stderr, "tokencount: skipping %s: %s\n", name, reason)
+			}
+			return nil
+		}
+		files = append(files, input{name: name})
+		return nil
+	})
+	return files
+}
+
+// readIgnoreFiles returns the patterns in the ignore files of dir, whose
+// path relative to the root of the walk is rel.
+func readIgnoreFiles(dir, rel string) []ignore.Pattern {
+	if rel == "." {
+		rel = ""
+	}
+	var patterns []ignore.Pattern
+	for _, name := range ignoreFiles {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			continue
+		}
+		patterns = append(patterns, ignore.Parse(rel, data)...)
+	}
+	return patterns
+}
+
+// sniffLen is how much of a file is read to decide whether it is binary,
+// as git does.
+const sniffLen = 8000
+
+// skipFile reports why the named file should not be counted: because it
+// is larger than maxSize bytes, if maxSize is positive, or because it
+// looks like a binary file. It returns "" if the file should be counted.
+func skipFile(name string, maxSize int64) (string, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if maxSize > 0 {
+		info, err := f.Stat()
+		if err != nil {
+			return "", err
+		}
+		if info.Size() > maxSize {
+			return fmt.Sprintf("larger than %d bytes", maxSize), nil
+		}
+	}
+	buf := make([]byte, sniffLen)
+	n, err := io.ReadFull(f, buf)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	if bytes.IndexByte(buf[:n], 0) >= 0 {
+		return "binary file", nil
+	}
+	return "", nil
+}
+
+// parseSize parses a size in bytes with an optional K, M, or G suffix,
+// in powers of 1024.
+func parseSize(s string) (int64, error) {
+	if s == "" {
+		return 0, nil
+	}
+	mult := int64(1)
+	switch strings.ToUpper(s[len(s)-1:]) {
+	case "K":
+		mult = 1 << 10
+	case "M":
+		mult = 1 << 20
+	case "G":
+		mult = 1 << 30
+	}
+	if mult > 1 {
+		s = s[:len(s)-1]
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", s)
+	}
+	return n * mult, nil
+}
+
+// globs is a flag.Value that collects the globs of a repeated flag.
+type globs []string
+
+func (g *globs) String() string { return strings.Join(*g, ",") }
+
+func (g *globs) Set(s string) error {
+	if _, ok := ignore.ParsePattern("", s); !ok {
+		return fmt.Errorf("invalid glob %q", s)
+	}
+	*g = append(*g, s)
+	return nil
+}